@@ -2,157 +2,442 @@ package data_struct
 
 import (
 	"errors"
+	"iter"
+	"math/rand/v2"
+	"sync"
 )
 
-type Rider interface {
-	Info() (int, int, int)
-	Update(start int, end int)
-}
+var ErrNotFound = errors.New("rider not found")
 
-type Horse struct {
-	Rider Rider
-	Right *Horse
-	Left  *Horse
+type Horse[T any] struct {
+	Rider T
+	Right *Horse[T]
+	Left  *Horse[T]
 }
 
-type MerryGo struct {
-	Head  *Horse
-	Tail  *Horse
-	Size  int
-	Count int
+/*
+MerryGo 원형 큐 형태의 회전목마
+
+모든 메서드는 내부 mutex로 보호되므로 여러 고루틴에서 동시에 호출해도 안전합니다.
+*/
+type MerryGo[T any] struct {
+	mu    sync.RWMutex
+	head  *Horse[T]
+	tail  *Horse[T]
+	size  int
+	count int
 }
 
-func NewMerryGo(size int) *MerryGo {
-	return &MerryGo{Size: size, Count: 0}
+func NewMerryGo[T any](size int) *MerryGo[T] {
+	return &MerryGo[T]{size: size, count: 0}
 }
 
 /*
 Append 해당 함수는 MerryGo에 Horse와 Rider를 추가합니다. 더 이상 MerryGo에 자리가 없을 경우 에러가 납니다
 
-Rider Rider: MerryGo에 들어갈 데이터
+rider T: MerryGo에 들어갈 데이터
 */
-func (m *MerryGo) Append(rider Rider) error {
-	if m.IsFull() {
+func (m *MerryGo[T]) Append(rider T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isFull() {
 		return errors.New("MerryGo is full")
 	}
 
-	newHorse := &Horse{Rider: rider}
-	if m.Head == nil && m.Tail == nil {
-		m.Head = newHorse
-		m.Tail = newHorse
-		m.Head.Left = newHorse
-		m.Head.Right = newHorse
-		m.Tail.Left = newHorse
-		m.Tail.Right = newHorse
-		m.Count++
+	m.insertBefore(m.head, &Horse[T]{Rider: rider})
+
+	return nil
+}
+
+/*
+InsertAt 해당 함수는 MerryGo의 index 위치에 Rider를 추가합니다. 0은 Head를 의미하며, Len() 이상의 값은 Tail 뒤에 추가합니다.
+
+index int: 추가할 위치
+rider T: MerryGo에 들어갈 데이터
+*/
+func (m *MerryGo[T]) InsertAt(index int, rider T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isFull() {
+		return errors.New("MerryGo is full")
+	}
+	if index < 0 {
+		return errors.New("index out of range")
+	}
+
+	newHorse := &Horse[T]{Rider: rider}
+	if index >= m.count {
+		m.insertBefore(m.head, newHorse)
 		return nil
 	}
 
-	newHorse.Right = m.Head
-	newHorse.Left = m.Tail
-	m.Tail.Right = newHorse
-	m.Tail = newHorse
-	m.Head.Left = m.Tail
-	m.Count++
+	if index == 0 {
+		m.pushFront(newHorse)
+		return nil
+	}
+	m.insertBefore(m.horseAt(index), newHorse)
 
 	return nil
 }
 
 /*
-PopTail 해당 함수는 MerryGo의 Tail에 해당하는 Horse를 빼냅니다.
+PopTail 해당 함수는 MerryGo의 Tail에 해당하는 Rider를 빼냅니다.
 */
-func (m *MerryGo) PopTail() (horse *Horse, err error) {
-	var popHorse *Horse
+func (m *MerryGo[T]) PopTail() (T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if m.IsEmpty() {
-		return popHorse, errors.New("MerryGo is empty")
+	if m.isEmpty() {
+		var zero T
+		return zero, errors.New("MerryGo is empty")
 	}
 
-	popHorse = m.Tail
-
-	m.Tail.Left.Right = m.Head
-	m.Tail = m.Tail.Left
-	m.Head.Left = m.Tail
+	popHorse := m.tail
+	m.unlink(popHorse)
 
-	m.Count--
-
-	return popHorse, nil
+	return popHorse.Rider, nil
 }
 
 /*
-PopHead 해당 함수는 MerryGo의 Head에 해당하는 Horse를 빼냅니다.
+PopHead 해당 함수는 MerryGo의 Head에 해당하는 Rider를 빼냅니다.
 */
-func (m *MerryGo) PopHead() (horse *Horse, err error) {
-	var popHorse *Horse
+func (m *MerryGo[T]) PopHead() (T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if m.IsEmpty() {
-		return popHorse, errors.New("MerryGo is empty")
+	if m.isEmpty() {
+		var zero T
+		return zero, errors.New("MerryGo is empty")
 	}
 
-	popHorse = m.Head
+	popHorse := m.head
+	m.unlink(popHorse)
 
-	m.Head.Right.Left = m.Tail
-	m.Head = m.Head.Right
-	m.Tail.Right = m.Head
+	return popHorse.Rider, nil
+}
 
-	m.Count--
+func (m *MerryGo[T]) IsFull() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isFull()
+}
 
-	return popHorse, nil
+func (m *MerryGo[T]) IsEmpty() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isEmpty()
 }
 
-func (m *MerryGo) IsFull() bool {
-	return m.Count == m.Size
+// Len MerryGo에 타고 있는 Rider의 수
+func (m *MerryGo[T]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.count
 }
 
-func (m *MerryGo) IsEmpty() bool {
-	return m.Count == 0
+// Cap MerryGo에 탈 수 있는 최대 Rider의 수
+func (m *MerryGo[T]) Cap() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.size
+}
+
+/*
+Resize MerryGo의 최대 크기를 변경합니다. 현재 타고 있는 Rider 수보다 작게 줄일 수는 없으므로, 줄이기 전에 Rider를 먼저 빼내야 합니다.
+
+size int: 변경할 최대 크기
+*/
+func (m *MerryGo[T]) Resize(size int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if size < 1 {
+		return errors.New("MerryGo size must be positive")
+	}
+	if size < m.count {
+		return errors.New("MerryGo size is smaller than rider count")
+	}
+
+	m.size = size
+	return nil
+}
+
+func (m *MerryGo[T]) isFull() bool {
+	return m.count >= m.size
+}
+
+func (m *MerryGo[T]) isEmpty() bool {
+	return m.count == 0
+}
+
+/*
+Peek MerryGo의 Head에 타고 있는 Rider를 빼내지 않고 리턴합니다.
+*/
+func (m *MerryGo[T]) Peek() (T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.isEmpty() {
+		var zero T
+		return zero, errors.New("MerryGo is empty")
+	}
+
+	return m.head.Rider, nil
 }
 
 /*
 Rotate MerryGo 내의 모든 Rider의 위치를 left로 이동시킵니다.
 */
-func (m *MerryGo) Rotate() error {
-	if m.IsEmpty() {
+func (m *MerryGo[T]) Rotate() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isEmpty() {
 		return errors.New("MerryGo is empty")
 	}
 
-	m.Head = m.Head.Right
-	m.Tail = m.Head
+	m.tail = m.head
+	m.head = m.head.Right
 	return nil
 }
 
 /*
-Display MerryGo 내의 모든 데이터를 Head -> Tail 순으로 순환하여 리턴합니다.
+Find Head -> Tail 순으로 match를 만족하는 첫번째 Rider와 그 위치를 찾습니다.
+
+return: Rider T, 위치 int, 찾았는지 여부 bool
 */
-func (m *MerryGo) Display() ([]Rider, error) {
-	if m.IsEmpty() {
-		return nil, errors.New("MerryGo is empty")
+func (m *MerryGo[T]) Find(match func(T) bool) (T, int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	horse, index := m.find(match)
+	if horse == nil {
+		var zero T
+		return zero, -1, false
+	}
+
+	return horse.Rider, index, true
+}
+
+/*
+Remove 해당 함수는 MerryGo 내에서 match를 만족하는 첫번째 Rider를 빼냅니다. 해당하는 Rider가 없을 경우 ErrNotFound를 리턴합니다.
+
+match func(T) bool: 빼낼 Rider의 조건
+*/
+func (m *MerryGo[T]) Remove(match func(T) bool) (T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	horse, _ := m.find(match)
+	if horse == nil {
+		var zero T
+		return zero, ErrNotFound
+	}
+	m.unlink(horse)
+
+	return horse.Rider, nil
+}
+
+/*
+MoveToFront match를 만족하는 첫번째 Rider를 Head 위치로 옮깁니다. 해당하는 Rider가 없을 경우 ErrNotFound를 리턴합니다.
+*/
+func (m *MerryGo[T]) MoveToFront(match func(T) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	horse, index := m.find(match)
+	if horse == nil {
+		return ErrNotFound
+	}
+	if index == 0 {
+		return nil
+	}
+
+	m.unlink(horse)
+	m.pushFront(horse)
+
+	return nil
+}
+
+/*
+MoveTo match를 만족하는 첫번째 Rider를 index 위치로 옮깁니다. 0은 Head를 의미하며, Len() 이상의 값은 Tail 위치로 옮깁니다.
+해당하는 Rider가 없을 경우 ErrNotFound를 리턴합니다.
+*/
+func (m *MerryGo[T]) MoveTo(match func(T) bool, index int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if index < 0 {
+		return errors.New("index out of range")
+	}
+	horse, current := m.find(match)
+	if horse == nil {
+		return ErrNotFound
+	}
+	if index >= m.count {
+		index = m.count - 1
+	}
+	if index == current {
+		return nil
 	}
 
-	RiderList := make([]Rider, m.Count)
-	start := m.Head
-	for i := 0; i < m.Count; i++ {
-		RiderList[i] = start.Rider
-		if start.Right == m.Head {
-			break
+	m.unlink(horse)
+	switch {
+	case index == 0:
+		m.pushFront(horse)
+	case index >= m.count:
+		m.insertBefore(m.head, horse)
+	default:
+		m.insertBefore(m.horseAt(index), horse)
+	}
+
+	return nil
+}
+
+// Shuffle MerryGo 내의 모든 Rider의 순서를 무작위로 섞습니다.
+func (m *MerryGo[T]) Shuffle() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	horses := make([]*Horse[T], 0, m.count)
+	horse := m.head
+	for i := 0; i < m.count; i++ {
+		horses = append(horses, horse)
+		horse = horse.Right
+	}
+	rand.Shuffle(len(horses), func(i, j int) { horses[i], horses[j] = horses[j], horses[i] })
+
+	for i, horse := range horses {
+		horse.Right = horses[(i+1)%len(horses)]
+		horse.Left = horses[(i+len(horses)-1)%len(horses)]
+	}
+	if len(horses) > 0 {
+		m.head = horses[0]
+		m.tail = horses[len(horses)-1]
+	}
+}
+
+// find Head -> Tail 순으로 match를 만족하는 Horse와 위치를 찾습니다. 없으면 nil, -1
+func (m *MerryGo[T]) find(match func(T) bool) (*Horse[T], int) {
+	horse := m.head
+	for i := 0; i < m.count; i++ {
+		if match(horse.Rider) {
+			return horse, i
 		}
-		start = start.Right
+		horse = horse.Right
 	}
+	return nil, -1
+}
 
-	return RiderList, nil
+// horseAt Head로부터 index 번째의 Horse를 리턴합니다. index는 0 <= index < count 이어야 합니다.
+func (m *MerryGo[T]) horseAt(index int) *Horse[T] {
+	horse := m.head
+	for i := 0; i < index; i++ {
+		horse = horse.Right
+	}
+	return horse
 }
 
-type Segment struct {
-	Start  int
-	End    int
-	Length int
+// insertBefore at의 왼쪽(at이 Head라면 Tail 뒤)에 Horse를 연결합니다. 비어있다면 유일한 Horse가 됩니다.
+func (m *MerryGo[T]) insertBefore(at *Horse[T], horse *Horse[T]) {
+	if m.isEmpty() {
+		horse.Left = horse
+		horse.Right = horse
+		m.head = horse
+		m.tail = horse
+		m.count++
+		return
+	}
+
+	horse.Right = at
+	horse.Left = at.Left
+	at.Left.Right = horse
+	at.Left = horse
+	if at == m.head {
+		m.tail = horse
+	}
+	m.count++
+}
+
+// pushFront Horse를 Head 위치에 연결합니다.
+func (m *MerryGo[T]) pushFront(horse *Horse[T]) {
+	m.insertBefore(m.head, horse)
+	m.head = horse
+	m.tail = horse.Left
+}
+
+// unlink 해당 Horse의 양 옆을 서로 연결하여 MerryGo에서 떼어냅니다.
+func (m *MerryGo[T]) unlink(horse *Horse[T]) {
+	if m.count == 1 {
+		m.head = nil
+		m.tail = nil
+		m.count = 0
+		return
+	}
+
+	horse.Left.Right = horse.Right
+	horse.Right.Left = horse.Left
+	if horse == m.head {
+		m.head = horse.Right
+	}
+	if horse == m.tail {
+		m.tail = horse.Left
+	}
+	m.count--
+}
+
+/*
+Display MerryGo 내의 모든 데이터를 Head -> Tail 순으로 순환하여 리턴합니다.
+
+리턴되는 슬라이스는 호출 시점의 스냅샷이므로 이후 MerryGo가 변경되어도 영향을 받지 않습니다.
+*/
+func (m *MerryGo[T]) Display() ([]T, error) {
+	riders := m.snapshot()
+	if len(riders) == 0 {
+		return nil, errors.New("MerryGo is empty")
+	}
+
+	return riders, nil
 }
 
-func (s *Segment) Info() (int, int, int) {
-	return s.Start, s.End, s.Length
+/*
+All Head -> Tail 순으로 위치와 Rider를 순회하는 iterator를 리턴합니다.
+
+순회는 호출 시점의 스냅샷을 대상으로 하므로, 순회 중에 MerryGo를 변경해도 안전합니다.
+*/
+func (m *MerryGo[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i, rider := range m.snapshot() {
+			if !yield(i, rider) {
+				return
+			}
+		}
+	}
 }
 
-func (s *Segment) Update(updateStart int, updateEnd int) {
-	s.Start = updateStart
-	s.End = updateEnd
+// Values Head -> Tail 순으로 Rider만 순회하는 iterator를 리턴합니다.
+func (m *MerryGo[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, rider := range m.snapshot() {
+			if !yield(rider) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot 현재 Rider들을 Head -> Tail 순으로 복사합니다.
+func (m *MerryGo[T]) snapshot() []T {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	riders := make([]T, m.count)
+	horse := m.head
+	for i := 0; i < m.count; i++ {
+		riders[i] = horse.Rider
+		horse = horse.Right
+	}
+	return riders
 }
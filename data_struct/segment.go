@@ -0,0 +1,116 @@
+package data_struct
+
+import (
+	"sync"
+	"time"
+)
+
+// Segment 회전 중 다른 고루틴에서 읽을 수 있으므로 변경되는 값은 mu로 보호합니다.
+type Segment struct {
+	mu       sync.Mutex
+	Id       string // 업로드 시 생성된 UUID
+	Start    int
+	End      int
+	Plays    int       // 재생된 횟수
+	Likes    int       // 좋아요 수
+	Uploaded time.Time // 업로드 시각
+
+	Durations    []float64 // 각 segment의 #EXTINF 길이
+	Renditions   []string  // 영상 디렉토리에 있는 화질 이름 목록, 비어있으면 화질 구분 없이 영상 디렉토리 바로 아래에 segment가 있음
+	Uploader     string
+	OriginalName string
+	Loudness     float64 // 업로드 시 측정한 통합 음량 (LUFS), 측정하지 않았으면 0
+}
+
+func (s *Segment) ID() string {
+	return s.Id
+}
+
+// Info 영상 디렉토리 내의 segment 구간 (시작, 끝 번호)
+func (s *Segment) Info() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Start, s.End
+}
+
+func (s *Segment) Update(updateStart int, updateEnd int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Start = updateStart
+	s.End = updateEnd
+}
+
+// Played 재생 횟수를 1 증가시킵니다.
+func (s *Segment) Played() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Plays++
+}
+
+// Duration 모든 segment 길이의 합 (초)
+func (s *Segment) Duration() float64 {
+	total := 0.0
+	for _, d := range s.Durations {
+		total += d
+	}
+	return total
+}
+
+// PlayTime 영상 전체의 재생 시간, #EXTINF 길이의 합을 그대로 사용합니다.
+func (s *Segment) PlayTime() time.Duration {
+	return time.Duration(s.Duration() * float64(time.Second))
+}
+
+// HasRendition 해당 화질로 변환된 segment가 있는지 확인합니다.
+func (s *Segment) HasRendition(name string) bool {
+	for _, rendition := range s.Renditions {
+		if rendition == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Like 좋아요 수를 1 증가시키고 증가된 값을 리턴합니다.
+func (s *Segment) Like() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Likes++
+	return s.Likes
+}
+
+// LikeCount 좋아요 수를 리턴합니다.
+func (s *Segment) LikeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Likes
+}
+
+// PlayCount 재생된 횟수를 리턴합니다.
+func (s *Segment) PlayCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Plays
+}
+
+func (s *Segment) UploadedAt() time.Time {
+	return s.Uploaded
+}
+
+/*
+Expired 만료 여부를 리턴합니다. 0 이하의 값은 제한 없음을 의미합니다.
+
+maxPlays int: 최대 재생 횟수
+ttl time.Duration: 업로드 이후 유지 시간
+*/
+func (s *Segment) Expired(maxPlays int, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if maxPlays > 0 && s.Plays >= maxPlays {
+		return true
+	}
+	if ttl > 0 && time.Since(s.Uploaded) >= ttl {
+		return true
+	}
+	return false
+}
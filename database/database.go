@@ -29,5 +29,5 @@ func InitDatabase() {
 	}
 
 	// 데이터베이스 마이그레이션 (테이블 생성)
-	DB.AutoMigrate(&models.Message{}, &models.Pixel{})
+	DB.AutoMigrate(&models.Message{}, &models.Pixel{}, &models.Video{}, &models.PlaylistState{}, &models.Channel{}, &models.Schedule{})
 }
@@ -1,6 +1,6 @@
 module Merry-Go
 
-go 1.22
+go 1.23
 
 require gorm.io/driver/sqlite v1.5.6
 
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"crypto/subtle"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"os"
+)
+
+const (
+	SHRINK_OLDEST       = "oldest"
+	SHRINK_LEAST_PLAYED = "least-played"
+)
+
+// 관리자 API 인증 토큰, 설정되어 있지 않으면 관리자 API를 사용할 수 없음
+var adminToken = os.Getenv("ADMIN_TOKEN")
+
+/*
+AdminAuth 관리자 API 요청의 X-Admin-Token 헤더를 ADMIN_TOKEN 환경 변수와 비교하는 미들웨어
+*/
+func AdminAuth(c *fiber.Ctx) error {
+	if adminToken == "" {
+		return c.Status(fiber.StatusForbidden).SendString("Admin API is disabled")
+	}
+
+	token := c.Get("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		return c.Status(fiber.StatusUnauthorized).SendString("Invalid admin token")
+	}
+
+	return c.Next()
+}
+
+// 용량 변경 요청
+type ResizeRequest struct {
+	Capacity int    `json:"capacity"`
+	Policy   string `json:"policy"` // 줄일 때 제거할 영상 선택 방식 - oldest(기본값), least-played
+}
+
+/* ResizeHandler 채널 Merry-Go의 최대 용량을 변경합니다. 현재 영상 수보다 작게 줄이는 경우 정책에 따라 영상을 제거합니다.
+ */
+func ResizeHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	var req ResizeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+	if req.Capacity < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("Capacity must be positive")
+	}
+
+	var pick func() (*data_struct.Segment, error)
+	switch req.Policy {
+	case "", SHRINK_OLDEST:
+		pick = ch.pickOldest
+	case SHRINK_LEAST_PLAYED:
+		pick = ch.pickLeastPlayed
+	default:
+		return c.Status(fiber.StatusBadRequest).SendString("Unknown policy: " + req.Policy)
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	evicted := []string{}
+	for ch.merryGo.Len() > req.Capacity {
+		victim, err := pick()
+		if err != nil {
+			break
+		}
+		err = ch.removeVideo(victim.ID())
+		if err != nil {
+			log.Println("Failed to remove video: ", err)
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to remove video")
+		}
+		log.Printf("[Resize] %s 영상 제거\n", victim.ID())
+		evicted = append(evicted, victim.ID())
+	}
+
+	err = ch.merryGo.Resize(req.Capacity)
+	if err != nil {
+		log.Println("Failed to resize Merry-Go: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to resize Merry-Go")
+	}
+	log.Printf("[Resize] Merry-Go 용량 변경: %d\n", req.Capacity)
+	ch.saveSettings()
+	ch.notifyMerryGoChanged()
+
+	return c.JSON(fiber.Map{
+		"status":   "success",
+		"capacity": ch.merryGo.Cap(),
+		"count":    ch.merryGo.Len(),
+		"evicted":  evicted,
+	})
+}
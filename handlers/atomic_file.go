@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic 같은 디렉토리의 임시 파일에 먼저 쓴 뒤 rename 하여, 중간에 실패해도 기존 파일이 깨지지 않도록 합니다.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, data); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// writeFileSync 파일을 쓰고 디스크에 반영될 때까지 기다립니다.
+func writeFileSync(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"Merry-Go/database"
+	"Merry-Go/models"
+	"Merry-Go/playlist"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+// 기본 채널 이름, 채널 경로 없는 기존 주소(/hls/playlist.m3u8, /ws, /uploadVideo ...)를 사용하며 DB에는 빈 문자열로 저장됩니다.
+const DEFAULT_CHANNEL = ""
+
+const (
+	defaultCapacity = 10
+	channelDir      = "c" // hls 디렉토리 아래 채널별 디렉토리를 모아두는 디렉토리, /hls/c/<채널 이름>/playlist.m3u8
+)
+
+// 채널 이름은 주소와 디렉토리 이름에 그대로 쓰이므로 영문 소문자, 숫자, - 만 허용
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+/*
+Channel 하나의 Merry-Go와 그 라이브 플레이리스트, 채팅, 픽셀 보드
+
+채널마다 회전 고루틴이 따로 돌고, HLS 파일은 채널 디렉토리 아래에 영상 디렉토리와 함께 저장됩니다.
+Merry-Go, 라이브 플레이리스트, 디스크 상의 플레이리스트와 segment 파일, DB를 함께 변경하는 작업은 mu로 직렬화합니다.
+*/
+type Channel struct {
+	Name string
+
+	mu             sync.Mutex
+	merryGo        *data_struct.MerryGo[*data_struct.Segment]
+	live           *playlist.Live
+	pendingDeletes map[string]bool // 삭제되었지만 아직 윈도우에 segment가 남아있어서 재생이 끝난 뒤 파일을 지울 영상
+	pinnedID       string          // 관리자가 고정한 영상 ID, 고정을 풀 때까지 항상 다음 영상으로 재생됨
+	strategy       RotationStrategy
+	paused         bool             // 관리자가 회전을 멈췄는지 여부
+	maxLength      float64          // 업로드할 수 있는 영상의 최대 길이 (초), 0 이면 MAX_VIDEO_LENGTH
+	lastSignature  string           // 마지막으로 알린 Merry-Go 상태, 바뀐 경우에만 알림
+	schedule       []*scheduleBlock // 편성 블록, 만든 순서대로
+	program        string           // 마지막으로 플레이리스트에 넣은 영상이 속한 편성 블록 이름, 편성 밖이면 빈 문자열
+	lastWasSlate   bool             // 마지막으로 플레이리스트에 넣은 영상이 슬레이트인지 여부
+	savedPositions map[string]int   // DB에 저장된 영상별 Position, 회전만 한 경우 다시 쓰지 않기 위해 사용
+	deleted        bool
+
+	// DB에 예약해 둔 sequence 값, 재시작하면 이 값에서 이어서 시작
+	reservedMediaSequence         int
+	reservedDiscontinuitySequence int
+
+	hlsDir     string        // 플레이리스트와 영상 디렉토리가 있는 디렉토리 (절대 경로)
+	wake       chan struct{} // 업로드 등으로 Merry-Go가 바뀌었을 때 회전 고루틴을 깨우기 위한 채널
+	reschedule chan struct{} // 건너뛰기, 다시 시작 등으로 재생 종료 시각이 바뀌었을 때 타이머를 다시 맞추기 위한 채널
+	stop       chan struct{} // 채널 삭제 시 회전 고루틴 종료
+
+	chat   *chatRoom
+	pixels *pixelBoard
+}
+
+// 채널 목록, 기본 채널은 항상 있음
+var channels = map[string]*Channel{}
+var channelsMu sync.RWMutex
+
+/*
+newChannel 채널을 생성하고 채팅, 픽셀 보드 고루틴을 실행합니다. 회전 고루틴은 rotateLoop 으로 따로 실행합니다.
+*/
+func newChannel(name string, capacity int, strategy RotationStrategy, maxLength float64) *Channel {
+	hlsDir := absHlsDir
+	if name != DEFAULT_CHANNEL {
+		hlsDir = filepath.Join(absHlsDir, channelDir, name)
+	}
+
+	ch := &Channel{
+		Name:           name,
+		merryGo:        data_struct.NewMerryGo[*data_struct.Segment](capacity),
+		pendingDeletes: map[string]bool{},
+		savedPositions: map[string]int{},
+		strategy:       strategy,
+		maxLength:      maxLength,
+		hlsDir:         hlsDir,
+		wake:           make(chan struct{}, 1),
+		reschedule:     make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		chat:           newChatRoom(name),
+		pixels:         newPixelBoard(name),
+	}
+	ch.live = playlist.NewLive(windowSize, ch.nextVideo)
+
+	go ch.chat.run(ch.stop)
+	go ch.pixels.run(ch.stop)
+	return ch
+}
+
+// lookupChannel 이름에 해당하는 채널, 빈 문자열은 기본 채널
+func lookupChannel(name string) (*Channel, bool) {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	channel, ok := channels[name]
+	return channel, ok
+}
+
+// requestChannel 요청 경로의 :channel 에 해당하는 채널, 경로에 채널이 없으면 기본 채널
+func requestChannel(c *fiber.Ctx) (*Channel, error) {
+	channel, ok := lookupChannel(c.Params("channel"))
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Channel not found")
+	}
+	return channel, nil
+}
+
+/*
+ChannelExists 요청 경로의 :channel 에 해당하는 채널이 없으면 404를 응답하는 미들웨어
+*/
+func ChannelExists(c *fiber.Ctx) error {
+	if _, err := requestChannel(c); err != nil {
+		return err
+	}
+	return c.Next()
+}
+
+// playlistFile 채널의 마스터 플레이리스트 파일 경로
+func (ch *Channel) playlistFile() string {
+	return filepath.Join(ch.hlsDir, PLAYLIST+".m3u8")
+}
+
+// hlsURL 채널의 HLS 디렉토리 주소 ex) /hls, /hls/c/<채널 이름>
+func (ch *Channel) hlsURL() string {
+	if ch.Name == DEFAULT_CHANNEL {
+		return "/hls"
+	}
+	return "/hls/" + channelDir + "/" + ch.Name
+}
+
+// stateName 채널의 플레이리스트 sequence 값을 저장하는 이름, 기본 채널은 이전 버전과 같은 이름을 사용
+func (ch *Channel) stateName() string {
+	if ch.Name == DEFAULT_CHANNEL {
+		return PLAYLIST
+	}
+	return PLAYLIST + SPLITER + ch.Name
+}
+
+// trimConfig 채널의 최대 길이를 적용한 자르기 설정
+func (ch *Channel) trimConfig() TrimConfig {
+	config := trimConfig
+	if ch.maxLength > 0 {
+		config.MaxLength = ch.maxLength
+	}
+	return config
+}
+
+/*
+saveSettings 채널의 용량, 회전 방식, 최대 길이를 DB에 저장합니다. 기본 채널은 빈 이름으로 저장됩니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) saveSettings() {
+	record := models.Channel{Name: ch.Name, Capacity: ch.merryGo.Cap(), Strategy: ch.strategy.Name(), MaxLength: ch.maxLength}
+	// 기본 채널의 이름은 빈 문자열이라 Save 로는 항상 INSERT 되므로 upsert 로 저장
+	result := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record)
+	if result.Error != nil {
+		log.Printf("Failed to save channel %s: %v\n", ch.Name, result.Error)
+	}
+}
+
+/*
+InitChannels 기본 채널을 만들고 채팅, 픽셀 보드를 실행합니다.
+
+fileMode 가 true 이면 DB에 저장된 설정을 기본 채널에 적용하고 다른 채널도 불러와서, 모든 채널의 Merry-Go를 복구하고 회전 고루틴을 실행합니다.
+*/
+func InitChannels(fileMode bool) error {
+	main := newChannel(DEFAULT_CHANNEL, defaultCapacity, loadRotationStrategy(), 0)
+	channelsMu.Lock()
+	channels[DEFAULT_CHANNEL] = main
+	channelsMu.Unlock()
+	if !fileMode {
+		return nil
+	}
+
+	var records []models.Channel
+	result := database.DB.Find(&records)
+	if result.Error != nil {
+		return result.Error
+	}
+	for _, record := range records {
+		strategy, ok := newRotationStrategy(record.Strategy)
+		// 기본 채널은 이미 만들었으므로 관리자 API로 바꾼 용량과 회전 방식만 적용
+		if record.Name == DEFAULT_CHANNEL {
+			if err := main.merryGo.Resize(max(record.Capacity, 1)); err != nil {
+				return err
+			}
+			if ok {
+				main.strategy = strategy
+			}
+			continue
+		}
+		if !ok {
+			strategy, _ = newRotationStrategy(defaultStrategy)
+		}
+		channel := newChannel(record.Name, max(record.Capacity, 1), strategy, record.MaxLength)
+		if err := channel.load(); err != nil {
+			return err
+		}
+		channelsMu.Lock()
+		channels[record.Name] = channel
+		channelsMu.Unlock()
+	}
+	if err := main.load(); err != nil {
+		return err
+	}
+
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	for _, channel := range channels {
+		go channel.rotateLoop()
+	}
+	return nil
+}
+
+// ChannelInfo 채널 목록 API 응답의 채널 하나
+type ChannelInfo struct {
+	Name      string  `json:"name"`
+	Capacity  int     `json:"capacity"`
+	Count     int     `json:"count"`
+	Strategy  string  `json:"strategy"`
+	MaxLength float64 `json:"max_length"`
+	Playlist  string  `json:"playlist"`
+}
+
+// info 채널 목록 API에 보여줄 채널 정보
+func (ch *Channel) info() ChannelInfo {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ChannelInfo{
+		Name:      ch.Name,
+		Capacity:  ch.merryGo.Cap(),
+		Count:     ch.merryGo.Len(),
+		Strategy:  ch.strategy.Name(),
+		MaxLength: ch.trimConfig().MaxLength,
+		Playlist:  ch.hlsURL() + "/" + PLAYLIST + ".m3u8",
+	}
+}
+
+/* ChannelListHandler 모든 채널을 이름 순서대로 리턴합니다. 기본 채널의 이름은 빈 문자열입니다.
+ */
+func ChannelListHandler(c *fiber.Ctx) error {
+	channelsMu.RLock()
+	list := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		list = append(list, channel)
+	}
+	channelsMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	infos := make([]ChannelInfo, len(list))
+	for i, channel := range list {
+		infos[i] = channel.info()
+	}
+	return c.JSON(infos)
+}
+
+// 채널 생성 요청
+type CreateChannelRequest struct {
+	Name      string  `json:"name"`
+	Capacity  int     `json:"capacity"`   // 0 이면 기본값 10
+	Strategy  string  `json:"strategy"`   // 비어있으면 round-robin
+	MaxLength float64 `json:"max_length"` // 0 이면 MAX_VIDEO_LENGTH
+}
+
+/* CreateChannelHandler 새 채널을 만들고 회전을 시작합니다. 채널은 /c/<이름> 에서 볼 수 있습니다.
+ */
+func CreateChannelHandler(c *fiber.Ctx) error {
+	var req CreateChannelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+	if !channelNamePattern.MatchString(req.Name) {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid channel name")
+	}
+	if req.Capacity == 0 {
+		req.Capacity = defaultCapacity
+	}
+	if req.Capacity < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("Capacity must be positive")
+	}
+	if req.MaxLength < 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("Max length must not be negative")
+	}
+	if req.Strategy == "" {
+		req.Strategy = defaultStrategy
+	}
+	strategy, ok := newRotationStrategy(req.Strategy)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("Unknown strategy: " + req.Strategy)
+	}
+
+	channelsMu.Lock()
+	if _, exists := channels[req.Name]; exists {
+		channelsMu.Unlock()
+		return c.Status(fiber.StatusConflict).SendString("Channel already exists")
+	}
+	channel := newChannel(req.Name, req.Capacity, strategy, req.MaxLength)
+	channels[req.Name] = channel
+	channelsMu.Unlock()
+
+	if err := os.MkdirAll(channel.hlsDir, os.ModePerm); err != nil {
+		log.Println("Failed to create channel directory: ", err)
+	}
+	channel.mu.Lock()
+	channel.saveSettings()
+	channel.mu.Unlock()
+	go channel.rotateLoop()
+	log.Printf("[Channel] %s 채널 생성\n", req.Name)
+
+	return c.Status(fiber.StatusCreated).JSON(channel.info())
+}
+
+/* DeleteChannelHandler 채널을 멈추고 영상, 채팅, 픽셀 보드, 플레이리스트를 모두 삭제합니다. 기본 채널은 삭제할 수 없습니다.
+ */
+func DeleteChannelHandler(c *fiber.Ctx) error {
+	name := c.Params("channel")
+	if name == DEFAULT_CHANNEL {
+		return c.Status(fiber.StatusBadRequest).SendString("Default channel cannot be deleted")
+	}
+
+	channelsMu.Lock()
+	channel, ok := channels[name]
+	delete(channels, name)
+	channelsMu.Unlock()
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Channel not found")
+	}
+
+	if err := channel.destroy(); err != nil {
+		log.Println("Failed to delete channel: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to delete channel")
+	}
+	log.Printf("[Channel] %s 채널 삭제\n", name)
+
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// destroy 채널의 고루틴을 멈추고 채널에 속한 파일과 DB 데이터를 모두 삭제합니다. 채널 목록에서 먼저 뺀 뒤 호출해야 합니다.
+func (ch *Channel) destroy() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.deleted = true
+	close(ch.stop)
+
+	var errs []error
+	for _, model := range []any{&models.Video{}, &models.Message{}, &models.Pixel{}, &models.Schedule{}} {
+		errs = append(errs, database.DB.Where("channel = ?", ch.Name).Delete(model).Error)
+	}
+	errs = append(errs, database.DB.Delete(&models.PlaylistState{}, "name = ?", ch.stateName()).Error)
+	errs = append(errs, database.DB.Delete(&models.Channel{}, "name = ?", ch.Name).Error)
+	errs = append(errs, os.RemoveAll(ch.hlsDir))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+/* DeleteVideoHandler id에 해당하는 영상을 채널의 Merry-Go와 플레이리스트에서 제거합니다.
+ */
+func DeleteVideoHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	id := c.Params("id")
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	err = ch.removeVideo(id)
+	if err != nil {
+		if errors.Is(err, data_struct.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Video not found")
+		}
+		log.Println("Failed to remove video: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to remove video")
+	}
+
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+/*
+removeVideo id에 해당하는 Rider를 MerryGo에서 빼내고 영상 파일을 삭제합니다.
+이미 라이브 플레이리스트 윈도우에 들어간 segment는 재생이 끝날 때까지 남겨두었다가 삭제합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) removeVideo(id string) error {
+	_, err := ch.merryGo.Remove(func(s *data_struct.Segment) bool { return s.ID() == id })
+	if err != nil {
+		return err
+	}
+	if ch.pinnedID == id {
+		ch.setPinned("")
+	}
+	deleteVideoRecord(id)
+	delete(ch.savedPositions, id)
+	ch.deleteVideoFiles(id)
+	log.Printf("[Remove] %s 영상 제거\n", id)
+	ch.persistMerryGo()
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+lookupValue 환경 변수를 parse 로 읽어옵니다. 없으면 기본값, 읽을 수 없거나 valid 를 통과하지 못하면 로그를 남기고 기본값
+
+valid 가 nil 이면 읽을 수 있는 값은 모두 사용합니다.
+*/
+func lookupValue[T any](key string, defaultValue T, parse func(string) (T, error), valid func(T) bool) T {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	parsed, err := parse(value)
+	if err != nil || (valid != nil && !valid(parsed)) {
+		log.Printf("Error parsing %s: %s\n", key, value)
+		return defaultValue
+	}
+	return parsed
+}
+
+// positive 0보다 큰 값인지 여부, lookup 함수의 valid 로 사용
+func positive[T int | float64 | time.Duration](value T) bool {
+	return value > 0
+}
+
+// lookupBool 환경 변수에서 true/false 를 읽어옵니다. 없거나 잘못된 값이면 기본값
+func lookupBool(key string, defaultValue bool) bool {
+	return lookupValue(key, defaultValue, strconv.ParseBool, nil)
+}
+
+// lookupInt 환경 변수에서 정수를 읽어옵니다. 없거나 잘못된 값이면 기본값
+func lookupInt(key string, defaultValue int, valid func(int) bool) int {
+	return lookupValue(key, defaultValue, strconv.Atoi, valid)
+}
+
+// lookupNonNegativeInt 환경 변수에서 0 이상의 정수를 읽어옵니다. 없거나 잘못된 값이면 기본값
+func lookupNonNegativeInt(key string, defaultValue int) int {
+	return lookupInt(key, defaultValue, func(number int) bool { return number >= 0 })
+}
+
+// lookupFloat 환경 변수에서 실수를 읽어옵니다. 없거나 잘못된 값이면 기본값
+func lookupFloat(key string, defaultValue float64, valid func(float64) bool) float64 {
+	return lookupValue(key, defaultValue, func(value string) (float64, error) { return strconv.ParseFloat(value, 64) }, valid)
+}
+
+// lookupDuration 환경 변수에서 Go duration 형식(30s, 5m, 2h)의 시간을 읽어옵니다. 없거나 잘못된 값이면 기본값
+func lookupDuration(key string, defaultValue time.Duration, valid func(time.Duration) bool) time.Duration {
+	return lookupValue(key, defaultValue, time.ParseDuration, valid)
+}
+
+// lookupList 환경 변수에서 쉼표로 구분된 목록을 읽어옵니다. 없으면 기본값
+func lookupList(key string, defaultValue string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists || strings.TrimSpace(value) == "" {
+		value = defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
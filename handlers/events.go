@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	EVENT_VIDEO_PUBLISHED = "video_published" // 업로드된 영상이 Merry-Go에 들어감
+	EVENT_JOB_FAILED      = "job_failed"      // 업로드된 영상 처리 실패
+	EVENT_MERRYGO_CHANGED = "merrygo_changed" // Merry-Go의 영상 구성이나 순서, 재생 중인 영상이 바뀜
+)
+
+// Event 서버에서 클라이언트로 보내는 알림, 해당 채널의 /wse 에 연결된 클라이언트에게만 전송
+type Event struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"` // 채널 이름, 기본 채널은 빈 문자열
+	JobID   string `json:"job_id,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// 이벤트를 받을 클라이언트 연결과 연결한 채널 이름, 연결 고루틴과 브로드캐스트 고루틴에서 함께 쓰므로 mutex로 보호
+var eventClients = make(map[*websocket.Conn]string)
+var eventClientsMu sync.Mutex
+
+// 이벤트 브로드캐스트 채널
+var eventBroadcast = make(chan Event, 64)
+
+// 웹소켓 연결 핸들러, 서버에서 보내기만 하고 클라이언트 메세지는 연결 종료 확인용으로만 읽음
+func HandleEventConnections(c *websocket.Conn) {
+	defer func(c *websocket.Conn) {
+		err := c.Close()
+		if err != nil {
+			log.Printf("error: %v", err)
+		}
+	}(c)
+
+	eventClientsMu.Lock()
+	eventClients[c] = c.Params("channel")
+	eventClientsMu.Unlock()
+
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			eventClientsMu.Lock()
+			delete(eventClients, c)
+			eventClientsMu.Unlock()
+			break
+		}
+	}
+}
+
+// 이벤트의 채널에 연결된 클라이언트에게 이벤트 전송
+func HandleEvents() {
+	for {
+		event := <-eventBroadcast
+
+		eventClientsMu.Lock()
+		for client, channel := range eventClients {
+			if channel != event.Channel {
+				continue
+			}
+			err := client.WriteJSON(event)
+			if err != nil {
+				log.Printf("error: %v", err)
+				_ = client.Close()
+				delete(eventClients, client)
+			}
+		}
+		eventClientsMu.Unlock()
+	}
+}
+
+// publishEvent 이벤트를 브로드캐스트 채널에 넣습니다. 채널이 가득 차 있으면 버립니다.
+func publishEvent(event Event) {
+	select {
+	case eventBroadcast <- event:
+	default:
+		log.Printf("Event dropped: %s\n", event.Type)
+	}
+}
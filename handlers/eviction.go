@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"log"
+	"time"
+)
+
+// 영상 만료 정책, 0 이하의 값은 제한 없음
+type EvictionPolicy struct {
+	MaxPlays      int           // 해당 횟수만큼 재생되면 제거
+	TTL           time.Duration // 업로드 후 해당 시간이 지나면 제거
+	ReplaceOldest bool          // Merry-Go가 꽉 찬 상태에서 업로드 시 가장 오래된 영상을 제거하고 추가
+}
+
+var evictionPolicy = loadEvictionPolicy()
+
+/*
+loadEvictionPolicy 환경 변수에서 만료 정책을 읽어옵니다.
+
+MERRYGO_MAX_PLAYS: 최대 재생 횟수 (예: 5)
+MERRYGO_TTL: 업로드 후 유지 시간 (예: 30m, 2h)
+MERRYGO_REPLACE_OLDEST: true 일 경우 꽉 찬 상태에서 가장 오래된 영상을 교체
+*/
+func loadEvictionPolicy() EvictionPolicy {
+	return EvictionPolicy{
+		MaxPlays:      lookupNonNegativeInt("MERRYGO_MAX_PLAYS", 0),
+		TTL:           lookupDuration("MERRYGO_TTL", 0, nil),
+		ReplaceOldest: lookupBool("MERRYGO_REPLACE_OLDEST", false),
+	}
+}
+
+/*
+evictExpired 만료 정책에 해당하는 영상을 모두 제거합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) evictExpired() error {
+	for rider := range ch.merryGo.Values() {
+		if !rider.Expired(evictionPolicy.MaxPlays, evictionPolicy.TTL) {
+			continue
+		}
+		err := ch.removeVideo(rider.ID())
+		if err != nil {
+			return err
+		}
+		log.Printf("[Evict] %s 영상 만료\n", rider.ID())
+	}
+
+	return nil
+}
+
+/*
+expireVideos 회전과 관계없이 만료된 영상을 제거합니다.
+
+재생이 끝날 때만 확인하면 회전을 멈춘 동안 업로드 후 유지 시간이 지난 영상이 남으므로 rotateLoop 에서 주기적으로 호출합니다.
+*/
+func (ch *Channel) expireVideos() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.deleted {
+		return
+	}
+	err := ch.evictExpired()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+/*
+evictOldest 업로드 시각이 가장 오래된 영상을 제거합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) evictOldest() error {
+	oldest, err := ch.pickOldest()
+	if err != nil {
+		return err
+	}
+	log.Printf("[Evict] %s 영상 교체 (가장 오래된 영상)\n", oldest.ID())
+
+	return ch.removeVideo(oldest.ID())
+}
+
+// pickOldest 업로드 시각이 가장 오래된 영상을 찾습니다.
+func (ch *Channel) pickOldest() (*data_struct.Segment, error) {
+	oldest, err := ch.merryGo.Peek()
+	if err != nil {
+		return nil, err
+	}
+
+	for rider := range ch.merryGo.Values() {
+		if rider.UploadedAt().Before(oldest.UploadedAt()) {
+			oldest = rider
+		}
+	}
+
+	return oldest, nil
+}
+
+// pickLeastPlayed 재생 횟수가 가장 적은 영상을 찾습니다. 같다면 더 오래된 영상을 고릅니다.
+func (ch *Channel) pickLeastPlayed() (*data_struct.Segment, error) {
+	least, err := ch.merryGo.Peek()
+	if err != nil {
+		return nil, err
+	}
+
+	for rider := range ch.merryGo.Values() {
+		if rider.PlayCount() < least.PlayCount() ||
+			(rider.PlayCount() == least.PlayCount() && rider.UploadedAt().Before(least.UploadedAt())) {
+			least = rider
+		}
+	}
+
+	return least, nil
+}
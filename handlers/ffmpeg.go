@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+/*
+runFfmpeg 함수는 자원 제한을 걸어서 ffmpeg를 실행하고 -progress 출력을 읽어서 진행률을 알립니다.
+
+args []string: ffmpeg 인자, -progress 인자는 여기서 추가합니다.
+totalSeconds float64: 입력 영상의 길이 (초), 0 이하이면 진행률을 계산하지 않음
+onProgress func(float64): 0 ~ 1 사이의 진행률을 받는 함수, nil 가능
+*/
+func runFfmpeg(args []string, totalSeconds float64, onProgress func(float64)) error {
+	_, err := runFfmpegOutput(args, totalSeconds, onProgress)
+	return err
+}
+
+// runFfmpegOutput runFfmpeg 와 같지만 ffmpeg의 stderr 출력을 리턴합니다. 필터의 측정 결과를 읽을 때 사용합니다.
+func runFfmpegOutput(args []string, totalSeconds float64, onProgress func(float64)) (string, error) {
+	args = append([]string{"-nostats", "-progress", "pipe:1"}, args...)
+	cmd, cancel := sandboxCommand(sandboxLimits.FfmpegTimeout, "ffmpeg", args...)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	// key=value 형식으로 주기적으로 출력되는 진행 상황 중 out_time_us 만 사용
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || onProgress == nil || totalSeconds <= 0 {
+			continue
+		}
+		switch key {
+		case "out_time_us":
+			microseconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || microseconds < 0 {
+				continue
+			}
+			onProgress(min(float64(microseconds)/1e6/totalSeconds, 1))
+		case "progress":
+			if value == "end" {
+				onProgress(1)
+			}
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		log.Println("ffmpeg command failed: ", err)
+		log.Println("ffmpeg stderr: ", stderr.String())
+		return "", fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	return stderr.String(), nil
+}
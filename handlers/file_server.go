@@ -1,12 +1,37 @@
 package handlers
 
 import (
+	"path"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 )
 
+// 영상별 segment 캐시 시간 (초), 영상 ID 디렉토리 아래의 segment는 내용이 바뀌지 않음
+const segmentMaxAge = "31536000"
+
 // 파일 서버 요청을 처리하는 핸들러
 // HTML 파일이 있는 디렉토리를 설정하고, 로그를 추가합니다.
 func FileServerHandler(c *fiber.Ctx) error {
 
 	return c.SendFile("static/html/index.html") // 적절한 파일 경로로 수정
 }
+
+/*
+HlsHeaders /hls 아래 파일의 CORS, 캐시 헤더를 설정하는 미들웨어
+
+플레이리스트(.m3u8)는 계속 바뀌므로 캐시하지 않고, 영상 디렉토리 아래의 segment(/hls/<영상 ID>/.../segN.ts)는
+같은 주소의 내용이 바뀌지 않으므로 CDN과 브라우저가 오래 캐시하도록 합니다.
+카메라 라이브의 segment(/hls/segN.ts)와 설정에 따라 다시 만드는 슬레이트는 제외합니다.
+*/
+func HlsHeaders(c *fiber.Ctx) error {
+	c.Set("Access-Control-Allow-Origin", "*")
+
+	filePath := strings.TrimPrefix(path.Clean(c.Path()), "/hls/")
+	if strings.HasSuffix(filePath, ".ts") && strings.Contains(filePath, "/") && !strings.HasPrefix(filePath, SLATE_ID+"/") {
+		c.Set("Cache-Control", "public, max-age="+segmentMaxAge+", immutable")
+	} else {
+		c.Set("Cache-Control", "no-cache")
+	}
+	return c.Next()
+}
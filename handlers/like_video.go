@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"github.com/gofiber/fiber/v2"
+)
+
+/* LikeVideoHandler id에 해당하는 영상의 좋아요 수를 1 증가시킵니다. likes 회전 방식에서 가중치로 사용합니다.
+ */
+func LikeVideoHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	id := c.Params("id")
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	rider, _, ok := ch.merryGo.Find(func(s *data_struct.Segment) bool { return s.ID() == id })
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Video not found")
+	}
+	likes := rider.Like()
+	persistLikes(rider)
+	ch.notifyMerryGoChanged()
+
+	return c.JSON(fiber.Map{"status": "success", "likes": likes})
+}
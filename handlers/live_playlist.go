@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"Merry-Go/database"
+	"Merry-Go/models"
+	"Merry-Go/playlist"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const defaultWindowSize = 5
+
+/*
+채널의 라이브 플레이리스트 윈도우 크기
+
+라이브 플레이리스트는 Merry-Go를 끝나지 않는 라이브 스트림으로 보여줍니다.
+윈도우가 모자랄 때마다 Merry-Go의 Head 영상을 꺼내 뒤에 붙이고 Merry-Go를 회전시키므로,
+Merry-Go의 Head는 다음에 윈도우에 들어갈 영상이 되고 지금 재생 중인 영상은 윈도우의 맨 앞 segment의 영상이 됩니다.
+*/
+var windowSize = loadWindowSize()
+
+// 한 번 저장할 때 미리 예약해 두는 sequence 값의 수
+const (
+	mediaSequenceReserve         = 1000
+	discontinuitySequenceReserve = 100
+)
+
+// loadWindowSize HLS_WINDOW_SIZE 환경 변수에서 플레이리스트에 보여줄 segment 수를 읽어옵니다.
+func loadWindowSize() int {
+	return lookupInt("HLS_WINDOW_SIZE", defaultWindowSize, positive)
+}
+
+/*
+nextVideo 회전 방식이 고른 영상을 꺼내 윈도우에 넣을 수 있도록 변환합니다.
+
+고정된 영상이 있다면 회전 방식과 관계없이 고정된 영상을 꺼내며, 나머지 영상의 순서는 그대로 유지됩니다.
+편성표가 있다면 꺼낸 영상이 재생되기 시작하는 시각(윈도우의 끝)의 편성에 맞는 영상 중에서 고르므로, 편성은 영상 경계에서 바뀝니다.
+편성에 맞는 영상이 없으면 모든 영상 중에서 고릅니다.
+
+Merry-Go가 비어있으면 슬레이트를 계속, 영상이 하나뿐이면 같은 영상이 연달아 나오지 않도록 슬레이트를 사이사이에 넣습니다.
+*/
+func (ch *Channel) nextVideo() (playlist.Video, bool) {
+	if slate, ok := slateVideo(); ok {
+		count := ch.merryGo.Len()
+		if count == 0 || (count == 1 && !ch.lastWasSlate) {
+			ch.lastWasSlate = true
+			return slate, true
+		}
+	}
+	ch.lastWasSlate = false
+
+	var head *data_struct.Segment
+	var ok bool
+	if pinned, _, found := ch.merryGo.Find(func(s *data_struct.Segment) bool { return s.ID() == ch.pinnedID }); ch.pinnedID != "" && found {
+		head, ok = takeRider(ch.merryGo, pinned)
+	} else {
+		eligible, program := ch.scheduledRiders(ch.live.End())
+		if len(eligibleRiders(ch.merryGo, eligible)) == 0 {
+			eligible = nil
+		}
+		if program != ch.program {
+			log.Printf("[Schedule] 편성 변경: %q -> %q\n", ch.program, program)
+			ch.program = program
+		}
+		head, ok = ch.strategy.Next(ch.merryGo, eligible)
+	}
+	if !ok {
+		return playlist.Video{}, false
+	}
+
+	start, _ := head.Info()
+	video := playlist.Video{ID: head.ID(), Durations: head.Durations, URIs: map[string][]string{}}
+	for _, rendition := range renditions {
+		for i := range head.Durations {
+			video.URIs[rendition.Name] = append(video.URIs[rendition.Name], segmentURI(head, rendition.Name, start+i))
+		}
+	}
+	return video, true
+}
+
+/*
+segmentURI 영상의 index 번째 segment를 가리키는 플레이리스트 기준 상대 경로 ex) <videoID>/720p/seg0.ts
+
+해당 화질이 없는 영상(화질 목록이 바뀌기 전에 올라온 영상)은 가지고 있는 첫번째 화질을,
+화질 구분이 없는 이전 버전의 영상은 <videoID>/seg0.ts 를 사용합니다.
+*/
+func segmentURI(segment *data_struct.Segment, rendition string, index int) string {
+	fileName := fmt.Sprintf(SEGNAME+"%d.ts", index)
+	if len(segment.Renditions) == 0 {
+		return path.Join(segment.ID(), fileName)
+	}
+	if !segment.HasRendition(rendition) {
+		rendition = segment.Renditions[0]
+	}
+	return path.Join(segment.ID(), rendition, fileName)
+}
+
+/*
+writePlaylist 현재 윈도우를 화질별 플레이리스트 파일에 쓰고, 이를 묶는 마스터 플레이리스트를 playlist.m3u8 에 씁니다.
+윈도우가 비어있으면 플레이리스트를 모두 삭제합니다.
+윈도우의 sequence 값이 예약해 둔 범위를 넘었다면 파일을 쓰기 전에 다시 예약해서 저장합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) writePlaylist() error {
+	if ch.live.Len() == 0 {
+		files := []string{ch.playlistFile()}
+		for _, rendition := range renditions {
+			files = append(files, filepath.Join(ch.hlsDir, renditionPlaylistName(rendition.Name)))
+		}
+		for _, file := range files {
+			err := os.Remove(file)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ch.savePlaylistState()
+
+	// 마스터 플레이리스트가 가리키는 플레이리스트를 먼저 씀
+	for _, rendition := range renditions {
+		err := writeFileAtomic(filepath.Join(ch.hlsDir, renditionPlaylistName(rendition.Name)), ch.live.Render(rendition.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(ch.playlistFile(), masterPlaylist().Encode())
+}
+
+/*
+untilNextRotation 지금 재생 중인 segment가 끝날 때까지 남은 시간, 윈도우가 비어있으면 false
+
+이미 지난 경우 0 이하의 값이 되어 바로 회전합니다.
+*/
+func (ch *Channel) untilNextRotation() (time.Duration, bool) {
+	deadline, ok := ch.live.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+/*
+deleteVideoFiles 영상 디렉토리를 삭제합니다. 윈도우에 아직 segment가 남아있다면 빠진 뒤에 삭제합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) deleteVideoFiles(id string) {
+	if ch.live.Contains(id) {
+		ch.pendingDeletes[id] = true
+		return
+	}
+	err := os.RemoveAll(filepath.Join(ch.hlsDir, id))
+	if err != nil {
+		log.Printf("Failed to delete video directory %s: %v\n", id, err)
+	}
+}
+
+// removePendingVideos 재생이 끝나 윈도우에서 빠진 삭제 대기 영상의 파일을 지웁니다.
+func (ch *Channel) removePendingVideos() {
+	for id := range ch.pendingDeletes {
+		if ch.live.Contains(id) {
+			continue
+		}
+		delete(ch.pendingDeletes, id)
+		ch.deleteVideoFiles(id)
+	}
+}
+
+/*
+loadPlaylistState 종료 전에 예약해 둔 sequence 값을 불러와서 이어서 시작하고, 고정했던 영상이 Merry-Go에 남아있다면 다시 고정합니다.
+
+loadFromDatabase 로 Merry-Go를 복구한 뒤에 호출해야 합니다.
+*/
+func (ch *Channel) loadPlaylistState() {
+	var state models.PlaylistState
+	result := database.DB.Limit(1).Find(&state, "name = ?", ch.stateName())
+	if result.Error != nil {
+		log.Printf("Failed to load playlist state: %v\n", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	// 예약해 둔 값은 종료 전 윈도우에 있던 sequence 번호보다 크므로 겹치지 않음
+	ch.live.Restore(state.MediaSequence, state.DiscontinuitySequence)
+	ch.reservedMediaSequence = state.MediaSequence
+	ch.reservedDiscontinuitySequence = state.DiscontinuitySequence
+
+	if _, _, found := ch.merryGo.Find(func(s *data_struct.Segment) bool { return s.ID() == state.Pinned }); state.Pinned != "" && found {
+		ch.pinnedID = state.Pinned
+	}
+}
+
+/*
+savePlaylistState 윈도우가 쓰는 sequence 값이 예약해 둔 범위를 넘으면 앞으로 쓸 값까지 미리 예약해서 저장합니다.
+
+예약한 범위 안에서는 재시작해도 예약해 둔 값에서 이어서 시작하면 되므로, segment가 넘어갈 때마다 저장하지 않습니다.
+*/
+func (ch *Channel) savePlaylistState() {
+	mediaSequence, discontinuitySequence := ch.live.Sequence()
+	// 윈도우 다음 segment의 번호, 윈도우의 모든 segment에 구분자가 붙어 있어도 넘지 않는 구분자 번호
+	nextMediaSequence := mediaSequence + ch.live.Len()
+	nextDiscontinuitySequence := discontinuitySequence + ch.live.Len() + 1
+	if nextMediaSequence <= ch.reservedMediaSequence && nextDiscontinuitySequence <= ch.reservedDiscontinuitySequence {
+		return
+	}
+	ch.storePlaylistState(nextMediaSequence+mediaSequenceReserve, nextDiscontinuitySequence+discontinuitySequenceReserve)
+}
+
+// setPinned 고정한 영상을 바꾸고 예약해 둔 sequence 값과 함께 저장합니다. 빈 문자열이면 고정을 풉니다.
+func (ch *Channel) setPinned(id string) {
+	ch.pinnedID = id
+	ch.storePlaylistState(ch.reservedMediaSequence, ch.reservedDiscontinuitySequence)
+}
+
+// storePlaylistState 예약할 sequence 값과 고정한 영상을 DB에 저장합니다.
+func (ch *Channel) storePlaylistState(mediaSequence int, discontinuitySequence int) {
+	state := models.PlaylistState{
+		Name:                  ch.stateName(),
+		MediaSequence:         mediaSequence,
+		DiscontinuitySequence: discontinuitySequence,
+		Pinned:                ch.pinnedID,
+	}
+	result := database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&state)
+	if result.Error != nil {
+		log.Printf("Failed to save playlist state: %v\n", result.Error)
+		return
+	}
+	ch.reservedMediaSequence = state.MediaSequence
+	ch.reservedDiscontinuitySequence = state.DiscontinuitySequence
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 이보다 조용한 영상은 무음으로 보고 음량을 맞추지 않음 (LUFS)
+const silenceLoudness = -70
+
+/*
+LoudnessConfig 영상마다 다른 음량을 EBU R128 기준으로 맞추는 설정
+
+Merry-Go에서 큰 소리의 영상과 작은 소리의 영상이 이어서 재생되어도 비슷한 크기로 들리도록, HLS로 변환할 때 ffmpeg loudnorm 필터를 두 번 실행합니다.
+*/
+type LoudnessConfig struct {
+	Enabled    bool
+	TargetLUFS float64 // 목표 통합 음량 (LUFS)
+	TruePeak   float64 // 최대 true peak (dBTP)
+	Range      float64 // 목표 음량 범위 (LU)
+}
+
+var loudnessConfig = loadLoudnessConfig()
+
+// loadLoudnessConfig LOUDNORM, LOUDNORM_TARGET 환경 변수에서 설정을 읽어옵니다.
+func loadLoudnessConfig() LoudnessConfig {
+	config := LoudnessConfig{TargetLUFS: -23, TruePeak: -1, Range: 11}
+
+	config.Enabled = lookupBool("LOUDNORM", config.Enabled)
+	// loudnorm 필터가 허용하는 범위
+	config.TargetLUFS = lookupFloat("LOUDNORM_TARGET", config.TargetLUFS, func(target float64) bool { return target >= -70 && target <= -5 })
+	return config
+}
+
+// filter 측정 없이 사용할 loudnorm 필터 옵션
+func (c LoudnessConfig) filter() string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s", formatLoudness(c.TargetLUFS), formatLoudness(c.TruePeak), formatLoudness(c.Range))
+}
+
+func formatLoudness(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// loudnessMeasurement 첫번째 loudnorm 실행의 측정 결과, ffmpeg가 문자열로 출력합니다.
+type loudnessMeasurement struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// Integrated 측정된 통합 음량 (LUFS), 무음이면 -inf
+func (m *loudnessMeasurement) Integrated() (float64, error) {
+	return strconv.ParseFloat(m.InputI, 64)
+}
+
+// Filter 측정 결과를 넣은 두번째 loudnorm 필터 옵션, 전체에 같은 증폭을 적용하도록 linear 모드를 사용합니다.
+func (m *loudnessMeasurement) Filter(config LoudnessConfig) string {
+	return fmt.Sprintf("%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		config.filter(), m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+}
+
+// parseLoudnessMeasurement ffmpeg stderr 끝에 출력된 loudnorm JSON 결과를 읽습니다.
+func parseLoudnessMeasurement(output string) (*loudnessMeasurement, error) {
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("loudnorm output not found")
+	}
+
+	var measurement loudnessMeasurement
+	if err := json.Unmarshal([]byte(output[start:end+1]), &measurement); err != nil {
+		return nil, fmt.Errorf("error parsing loudnorm output: %v", err)
+	}
+	return &measurement, nil
+}
+
+/*
+measureLoudness 함수는 loudnorm 필터를 측정 모드로 실행하여 영상의 clip 구간의 음량을 측정합니다.
+
+return: 측정 결과 *loudnessMeasurement (오디오가 없으면 nil), error
+*/
+func measureLoudness(inputFilePath string, config LoudnessConfig, clip clipRange, duration float64, onProgress func(float64)) (*loudnessMeasurement, error) {
+	hasAudio, err := hasAudioStream(inputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAudio {
+		return nil, nil
+	}
+
+	args := append([]string{"-hide_banner"}, ffmpegInputArgs(inputFilePath, clip)...)
+	args = append(args, "-map", "0:a:0", "-af", config.filter()+":print_format=json")
+	args = append(args, ffmpegThreadArgs()...)
+	args = append(args, "-f", "null", "-")
+
+	output, err := runFfmpegOutput(args, clip.Length(duration), onProgress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure loudness: %w", err)
+	}
+	return parseLoudnessMeasurement(output)
+}
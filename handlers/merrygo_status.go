@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RiderStatus Merry-Go 상태 API 응답의 영상 하나
+type RiderStatus struct {
+	CarouselItem
+	Position   int       `json:"position"` // Head = 0, 다음에 플레이리스트에 들어갈 영상
+	SegStart   int       `json:"seg_start"`
+	SegEnd     int       `json:"seg_end"`
+	PlayCount  int       `json:"play_count"`
+	Likes      int       `json:"likes"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// NowPlaying 지금 재생 중인 영상
+type NowPlaying struct {
+	Id        string    `json:"id"`
+	EndsAt    time.Time `json:"ends_at"`
+	Remaining float64   `json:"remaining"` // 재생이 끝날 때까지 남은 시간 (초)
+}
+
+// MerryGoStatus Merry-Go 상태 API 응답
+type MerryGoStatus struct {
+	Channel        string        `json:"channel"` // 채널 이름, 기본 채널은 빈 문자열
+	Capacity       int           `json:"capacity"`
+	Count          int           `json:"count"`
+	Riders         []RiderStatus `json:"riders"`
+	Current        *NowPlaying   `json:"current"`
+	NextRotationIn float64       `json:"next_rotation_in"` // 플레이리스트가 다음 segment로 넘어갈 때까지 남은 시간 (초)
+	Paused         bool          `json:"paused"`
+	Pinned         string        `json:"pinned,omitempty"`  // 고정된 영상 ID
+	Strategy       string        `json:"strategy"`          // 회전 방식
+	Program        string        `json:"program,omitempty"` // 지금 플레이리스트를 채우는 편성 블록 이름
+}
+
+/*
+merryGoStatus 채널의 현재 Merry-Go와 라이브 플레이리스트의 상태
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) merryGoStatus() MerryGoStatus {
+	status := MerryGoStatus{Channel: ch.Name, Capacity: ch.merryGo.Cap(), Riders: []RiderStatus{}, Paused: ch.paused, Pinned: ch.pinnedID, Strategy: ch.strategy.Name(), Program: ch.program}
+
+	for position, segment := range ch.merryGo.All() {
+		start, end := segment.Info()
+		status.Riders = append(status.Riders, RiderStatus{
+			CarouselItem: ch.carouselItem(segment),
+			Position:     position,
+			SegStart:     start,
+			SegEnd:       end,
+			PlayCount:    segment.PlayCount(),
+			Likes:        segment.LikeCount(),
+			UploadedAt:   segment.UploadedAt(),
+		})
+	}
+	status.Count = len(status.Riders)
+
+	if id, endsAt, ok := ch.live.CurrentVideo(); ok {
+		status.Current = &NowPlaying{Id: id, EndsAt: endsAt, Remaining: max(time.Until(endsAt).Seconds(), 0)}
+	}
+	if interval, ok := ch.untilNextRotation(); ok && !ch.paused {
+		status.NextRotationIn = max(interval.Seconds(), 0)
+	}
+	return status
+}
+
+/*
+statusSignature 알림 여부를 정하기 위한 값, 남은 시간처럼 계속 바뀌는 값은 제외
+
+썸네일 파일을 확인하는 merryGoStatus 와 달리 메모리의 값만 사용하므로 segment가 넘어갈 때마다 계산해도 부담이 적습니다.
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) statusSignature() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d/%d|%t|%s|%s|%s", ch.merryGo.Len(), ch.merryGo.Cap(), ch.paused, ch.pinnedID, ch.strategy.Name(), ch.program)
+	if id, _, ok := ch.live.CurrentVideo(); ok {
+		builder.WriteString("|" + id)
+	}
+	for segment := range ch.merryGo.Values() {
+		fmt.Fprintf(&builder, "|%s:%d:%d", segment.ID(), segment.PlayCount(), segment.LikeCount())
+	}
+	return builder.String()
+}
+
+/*
+notifyMerryGoChanged 채널의 Merry-Go 상태가 마지막으로 알린 상태와 다르면 채널의 /wse 로 알립니다. 보낼 상태는 바뀐 경우에만 만듭니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) notifyMerryGoChanged() {
+	signature := ch.statusSignature()
+	if signature == ch.lastSignature {
+		return
+	}
+	ch.lastSignature = signature
+	publishEvent(Event{Type: EVENT_MERRYGO_CHANGED, Channel: ch.Name, Data: ch.merryGoStatus()})
+}
+
+/* MerryGoStatusHandler 채널 Merry-Go의 용량, 영상 수, 재생 순서대로의 영상 목록과 지금 재생 중인 영상을 리턴합니다.
+ */
+func MerryGoStatusHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+
+	ch.mu.Lock()
+	status := ch.merryGoStatus()
+	ch.mu.Unlock()
+
+	return c.JSON(status)
+}
@@ -4,24 +4,32 @@ import (
 	"Merry-Go/database"
 	"Merry-Go/models"
 	"log"
+	"sync"
 
 	"github.com/gofiber/websocket/v2"
 )
 
-// 클라이언트와의 연결을 추적
-var clients = make(map[*websocket.Conn]bool)
-
-// 메시지 브로드캐스트 채널
-var broadcast = make(chan Message)
-
 // Message struct to hold the message data
 type Message struct {
 	Username string `json:"username"`
 	Message  string `json:"message"`
 }
 
+// chatRoom 채널 하나의 채팅방
+type chatRoom struct {
+	channel   string
+	clients   map[*websocket.Conn]bool // 클라이언트와의 연결을 추적
+	clientsMu sync.Mutex               // 연결 고루틴과 run 고루틴이 함께 clients 를 변경하므로 잠금
+	closed    bool                     // 채널이 삭제되어 더 이상 연결을 받지 않음
+	broadcast chan Message             // 메시지 브로드캐스트 채널
+}
+
+func newChatRoom(channel string) *chatRoom {
+	return &chatRoom{channel: channel, clients: make(map[*websocket.Conn]bool), broadcast: make(chan Message)}
+}
+
 // 초기 ws 연결 시 클라이언트와 소통하는 부분
-// 웹소켓 연결 핸들러
+// 웹소켓 연결 핸들러, 경로의 채널 채팅방에 연결
 func HandleConnections(c *websocket.Conn) {
 	defer func(c *websocket.Conn) {
 		err := c.Close()
@@ -30,8 +38,14 @@ func HandleConnections(c *websocket.Conn) {
 		}
 	}(c)
 
+	ch, ok := lookupChannel(c.Params("channel"))
+	if !ok {
+		return
+	}
+	room := ch.chat
+
 	var msgs []models.Message
-	result := database.DB.Order("created_at desc").Limit(50).Find(&msgs) // 최근 50개만 읽어옴,
+	result := database.DB.Where("channel = ?", room.channel).Order("created_at desc").Limit(50).Find(&msgs) // 최근 50개만 읽어옴,
 	if result.Error != nil {
 		log.Printf("50 Message Read error: %v", result.Error)
 	}
@@ -49,41 +63,80 @@ func HandleConnections(c *websocket.Conn) {
 	if err := c.WriteJSON(apiMessages); err != nil {
 		log.Printf("error: %v", err)
 	}
-	clients[c] = true
+	if !room.join(c) {
+		return
+	}
 
 	for {
 		var msg Message
 		err := c.ReadJSON(&msg)
 		if err != nil {
 			log.Printf("error: %v", err)
-			delete(clients, c)
+			room.leave(c)
 			break
 		}
-		broadcast <- msg
+		select {
+		case room.broadcast <- msg:
+		case <-ch.stop:
+			return
+		}
 	}
 }
 
-// 연결 이후 클라이언트와 소통하는 부분
-func HandleMessages() {
+// join 연결을 추가합니다. 이미 채널이 삭제되었으면 false
+func (room *chatRoom) join(c *websocket.Conn) bool {
+	room.clientsMu.Lock()
+	defer room.clientsMu.Unlock()
+	if room.closed {
+		return false
+	}
+	room.clients[c] = true
+	return true
+}
+
+// leave 연결을 제거합니다.
+func (room *chatRoom) leave(c *websocket.Conn) {
+	room.clientsMu.Lock()
+	delete(room.clients, c)
+	room.clientsMu.Unlock()
+}
+
+// 연결 이후 클라이언트와 소통하는 부분, 채널이 삭제되면 연결을 모두 끊고 종료
+func (room *chatRoom) run(stop <-chan struct{}) {
 	for {
-		msg := <-broadcast
+		var msg Message
+		select {
+		case msg = <-room.broadcast:
+		case <-stop:
+			room.clientsMu.Lock()
+			room.closed = true
+			for client := range room.clients {
+				_ = client.Close()
+				delete(room.clients, client)
+			}
+			room.clientsMu.Unlock()
+			return
+		}
 
 		// 메세지 DB에 저장
-		createMessage(msg)
+		room.createMessage(msg)
 
-		for client := range clients {
+		room.clientsMu.Lock()
+		for client := range room.clients {
 			err := client.WriteJSON(msg)
 			if err != nil {
 				log.Printf("error: %v", err)
 				_ = client.Close()
-				delete(clients, client)
+				delete(room.clients, client)
 			}
 		}
+		room.clientsMu.Unlock()
 	}
 }
 
-func createMessage(msg Message) {
+func (room *chatRoom) createMessage(msg Message) {
 	message := new(models.Message)
+	message.Channel = room.channel
 	message.UserName = msg.Username
 	message.Message = msg.Message
 
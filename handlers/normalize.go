@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/*
+Profile 업로드된 영상을 맞출 공통 코덱 설정
+
+서로 다른 코덱, 해상도, 프레임 레이트의 영상이 구분자 태그만으로 이어지면 재생하지 못하는 플레이어가 많아서,
+Merry-Go에 태우기 전에 모든 영상을 같은 설정으로 맞춥니다.
+*/
+type Profile struct {
+	VideoCodec  string // ffprobe codec_name
+	PixelFormat string
+	Width       int
+	Height      int
+	FrameRate   float64
+	AudioCodec  string // ffprobe codec_name
+	SampleRate  int
+}
+
+// 기본 공통 코덱 설정 - H.264 yuv420p 1280x720 30fps, AAC 48kHz
+var defaultProfile = Profile{
+	VideoCodec:  "h264",
+	PixelFormat: "yuv420p",
+	Width:       1280,
+	Height:      720,
+	FrameRate:   30,
+	AudioCodec:  "aac",
+	SampleRate:  48000,
+}
+
+// NormalizeConfig 업로드 시 공통 코덱 설정으로 맞출지 여부와 대상 설정
+type NormalizeConfig struct {
+	Enabled bool
+	Profile Profile
+}
+
+var normalizeConfig = loadNormalizeConfig()
+
+// loadNormalizeConfig NORMALIZE, NORMALIZE_RESOLUTION, NORMALIZE_FPS 환경 변수에서 설정을 읽어옵니다.
+func loadNormalizeConfig() NormalizeConfig {
+	config := NormalizeConfig{Profile: defaultProfile}
+
+	config.Enabled = lookupBool("NORMALIZE", config.Enabled)
+
+	if value, exists := os.LookupEnv("NORMALIZE_RESOLUTION"); exists {
+		width, height, err := parseResolution(value)
+		if err != nil {
+			log.Printf("Error parsing NORMALIZE_RESOLUTION: %s\n", value)
+		} else {
+			config.Profile.Width = width
+			config.Profile.Height = height
+		}
+	}
+
+	config.Profile.FrameRate = lookupFloat("NORMALIZE_FPS", config.Profile.FrameRate, positive)
+
+	return config
+}
+
+// probeStream ffprobe -show_streams 결과 중 필요한 값
+type probeStream struct {
+	CodecType    string `json:"codec_type"`
+	CodecName    string `json:"codec_name"`
+	PixelFormat  string `json:"pix_fmt"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	AvgFrameRate string `json:"avg_frame_rate"` // ex) 30000/1001
+	SampleRate   string `json:"sample_rate"`
+}
+
+// FrameRate avg_frame_rate 를 초당 프레임 수로 변환합니다. 알 수 없으면 0
+func (s probeStream) FrameRate() float64 {
+	numStr, denStr, found := strings.Cut(s.AvgFrameRate, "/")
+	num, err := strconv.ParseFloat(numStr, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return num
+	}
+	den, err := strconv.ParseFloat(denStr, 64)
+	if err != nil || den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+// videoProbe 영상 파일의 첫번째 비디오, 오디오 스트림 정보
+type videoProbe struct {
+	Video *probeStream
+	Audio *probeStream
+}
+
+/*
+probeVideo 함수는 FFprobe를 사용하여 영상의 스트림 정보를 가져옵니다.
+
+return: 스트림 정보 *videoProbe, error
+*/
+func probeVideo(filePath string) (*videoProbe, error) {
+	cmd, cancel := probeCommand("-v", "error", "-show_entries",
+		"stream=codec_type,codec_name,pix_fmt,width,height,avg_frame_rate,sample_rate", "-of", "json", filePath)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error executing ffprobe command: %v, stderr: %s", err, stderr.String())
+	}
+
+	var result struct {
+		Streams []probeStream `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("error parsing ffprobe output: %v", err)
+	}
+
+	probe := &videoProbe{}
+	for i := range result.Streams {
+		stream := &result.Streams[i]
+		switch {
+		case stream.CodecType == "video" && probe.Video == nil:
+			probe.Video = stream
+		case stream.CodecType == "audio" && probe.Audio == nil:
+			probe.Audio = stream
+		}
+	}
+	return probe, nil
+}
+
+/*
+Mismatches 영상이 공통 코덱 설정과 다른 항목을 리턴합니다. 비어있으면 변환할 필요가 없습니다.
+*/
+func (p Profile) Mismatches(probe *videoProbe) []string {
+	var mismatches []string
+	if probe.Video == nil {
+		return []string{"video stream"}
+	}
+	if probe.Video.CodecName != p.VideoCodec {
+		mismatches = append(mismatches, "video codec "+probe.Video.CodecName)
+	}
+	if probe.Video.PixelFormat != p.PixelFormat {
+		mismatches = append(mismatches, "pixel format "+probe.Video.PixelFormat)
+	}
+	if probe.Video.Width != p.Width || probe.Video.Height != p.Height {
+		mismatches = append(mismatches, fmt.Sprintf("resolution %dx%d", probe.Video.Width, probe.Video.Height))
+	}
+	if math.Abs(probe.Video.FrameRate()-p.FrameRate) > 0.01 {
+		mismatches = append(mismatches, "frame rate "+probe.Video.AvgFrameRate)
+	}
+
+	if probe.Audio == nil {
+		return append(mismatches, "audio stream")
+	}
+	if probe.Audio.CodecName != p.AudioCodec {
+		mismatches = append(mismatches, "audio codec "+probe.Audio.CodecName)
+	}
+	if probe.Audio.SampleRate != strconv.Itoa(p.SampleRate) {
+		mismatches = append(mismatches, "sample rate "+probe.Audio.SampleRate)
+	}
+	return mismatches
+}
+
+/*
+normalizeVideo 함수는 영상이 공통 코덱 설정과 다를 경우에만 다시 인코딩합니다.
+
+화면 비율은 유지하고 남는 부분은 검은 여백으로 채우며, 오디오가 없는 영상에는 무음 오디오를 넣습니다.
+
+clip clipRange: 잘라낼 구간, 다시 인코딩하는 경우 해당 구간만 변환
+duration float64, onProgress func(float64): 영상 길이(초)와 변환 진행률을 받는 함수
+
+return: 변환된 파일 경로 string (변환하지 않았으면 입력 경로 그대로), 변환 여부 bool, error
+*/
+func normalizeVideo(inputFilePath string, profile Profile, clip clipRange, duration float64, onProgress func(float64)) (string, bool, error) {
+	probe, err := probeVideo(inputFilePath)
+	if err != nil {
+		return "", false, err
+	}
+	if probe.Video == nil {
+		return "", false, fmt.Errorf("no video stream in %s", inputFilePath)
+	}
+
+	mismatches := profile.Mismatches(probe)
+	if len(mismatches) == 0 {
+		return inputFilePath, false, nil
+	}
+	log.Printf("[Normalize] %s 변환 필요: %s\n", inputFilePath, strings.Join(mismatches, ", "))
+
+	outputFilePath := strings.TrimSuffix(inputFilePath, filepath.Ext(inputFilePath)) + ".normalized.mp4"
+	args := append([]string{"-y"}, ffmpegInputArgs(inputFilePath, clip)...)
+	audioInput := "0:a:0"
+	if probe.Audio == nil {
+		args = append(args, "-f", "lavfi", "-i", fmt.Sprintf("anullsrc=channel_layout=stereo:sample_rate=%d", profile.SampleRate))
+		audioInput = "1:a:0"
+	}
+	args = append(args, "-map", "0:v:0", "-map", audioInput,
+		"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s",
+			profile.Width, profile.Height, profile.Width, profile.Height, strconv.FormatFloat(profile.FrameRate, 'f', -1, 64)),
+		"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", profile.PixelFormat,
+		"-c:a", "aac", "-ar", strconv.Itoa(profile.SampleRate), "-ac", "2")
+	if probe.Audio == nil {
+		args = append(args, "-shortest")
+	}
+	args = append(args, ffmpegThreadArgs()...)
+	args = append(args, outputFilePath)
+
+	if err := runFfmpeg(args, clip.Length(duration), onProgress); err != nil {
+		_ = os.Remove(outputFilePath)
+		return "", false, fmt.Errorf("failed to normalize video: %w", err)
+	}
+
+	return outputFilePath, true, nil
+}
@@ -4,25 +4,42 @@ import (
 	"Merry-Go/database"
 	"Merry-Go/models"
 	"log"
+	"strings"
+	"sync"
 
 	"github.com/gofiber/websocket/v2"
 	"gorm.io/gorm"
 )
 
-// 클라이언트와의 연결을 추적
-var pixelClients = make(map[*websocket.Conn]bool)
-
-// 메시지 브로드캐스트 채널
-var pixelBroadcast = make(chan PixelMsg)
-
 // Message struct to hold the message data
 type PixelMsg struct {
 	Id    string `json:"id"`
 	Color string `json:"color"`
 }
 
+// pixelBoard 채널 하나의 픽셀 보드
+type pixelBoard struct {
+	channel   string
+	clients   map[*websocket.Conn]bool // 클라이언트와의 연결을 추적
+	clientsMu sync.Mutex               // 연결 고루틴과 run 고루틴이 함께 clients 를 변경하므로 잠금
+	closed    bool                     // 채널이 삭제되어 더 이상 연결을 받지 않음
+	broadcast chan PixelMsg            // 메시지 브로드캐스트 채널
+}
+
+func newPixelBoard(channel string) *pixelBoard {
+	return &pixelBoard{channel: channel, clients: make(map[*websocket.Conn]bool), broadcast: make(chan PixelMsg)}
+}
+
+// pixelKey DB에 저장하는 픽셀 ID, 채널마다 같은 픽셀 ID를 쓰므로 기본 채널이 아니면 채널 이름을 앞에 붙임
+func (board *pixelBoard) pixelKey(id string) string {
+	if board.channel == DEFAULT_CHANNEL {
+		return id
+	}
+	return board.channel + "/" + id
+}
+
 // 초기 ws 연결 시 클라이언트와 소통하는 부분
-// 웹소켓 연결 핸들러
+// 웹소켓 연결 핸들러, 경로의 채널 픽셀 보드에 연결
 func HandlePixelConnections(c *websocket.Conn) {
 	defer func(c *websocket.Conn) {
 		err := c.Close()
@@ -31,8 +48,14 @@ func HandlePixelConnections(c *websocket.Conn) {
 		}
 	}(c)
 
+	ch, ok := lookupChannel(c.Params("channel"))
+	if !ok {
+		return
+	}
+	board := ch.pixels
+
 	var pixels []models.Pixel
-	result := database.DB.Find(&pixels)
+	result := database.DB.Where("channel = ?", board.channel).Find(&pixels)
 	if result.Error != nil {
 		log.Printf("All Pixel Read error: %v", result.Error)
 	}
@@ -40,50 +63,89 @@ func HandlePixelConnections(c *websocket.Conn) {
 	// 연결된 클라이언트에 메시지 히스토리 전송
 	for _, pixel := range pixels {
 		msg := new(PixelMsg)
-		msg.Id = pixel.Id
+		msg.Id = strings.TrimPrefix(pixel.Id, board.pixelKey(""))
 		msg.Color = pixel.Color
 
 		if err := c.WriteJSON(msg); err != nil {
 			log.Printf("error: %v", err)
 		}
 	}
-	pixelClients[c] = true
+	if !board.join(c) {
+		return
+	}
 
 	for {
 		var msg PixelMsg
 		err := c.ReadJSON(&msg)
 		if err != nil {
 			log.Printf("error: %v", err)
-			delete(pixelClients, c)
+			board.leave(c)
 			break
 		}
-		pixelBroadcast <- msg
+		select {
+		case board.broadcast <- msg:
+		case <-ch.stop:
+			return
+		}
+	}
+}
+
+// join 연결을 추가합니다. 이미 채널이 삭제되었으면 false
+func (board *pixelBoard) join(c *websocket.Conn) bool {
+	board.clientsMu.Lock()
+	defer board.clientsMu.Unlock()
+	if board.closed {
+		return false
 	}
+	board.clients[c] = true
+	return true
 }
 
-// 연결 이후 클라이언트와 소통하는 부분
-func HandlePixelMessages() {
+// leave 연결을 제거합니다.
+func (board *pixelBoard) leave(c *websocket.Conn) {
+	board.clientsMu.Lock()
+	delete(board.clients, c)
+	board.clientsMu.Unlock()
+}
+
+// 연결 이후 클라이언트와 소통하는 부분, 채널이 삭제되면 연결을 모두 끊고 종료
+func (board *pixelBoard) run(stop <-chan struct{}) {
 	for {
-		msg := <-pixelBroadcast
+		var msg PixelMsg
+		select {
+		case msg = <-board.broadcast:
+		case <-stop:
+			board.clientsMu.Lock()
+			board.closed = true
+			for client := range board.clients {
+				_ = client.Close()
+				delete(board.clients, client)
+			}
+			board.clientsMu.Unlock()
+			return
+		}
 
 		// 픽셀 DB에 저장
-		upsertPixel(msg)
+		board.upsertPixel(msg)
 
-		for client := range pixelClients {
+		board.clientsMu.Lock()
+		for client := range board.clients {
 			err := client.WriteJSON(msg)
 			if err != nil {
 				log.Printf("error: %v", err)
 				_ = client.Close()
-				delete(pixelClients, client)
+				delete(board.clients, client)
 			}
 		}
+		board.clientsMu.Unlock()
 	}
 }
 
-func upsertPixel(msg PixelMsg) {
+func (board *pixelBoard) upsertPixel(msg PixelMsg) {
 	pixel := new(models.Pixel)
 	log.Println(msg)
-	pixel.Id = msg.Id
+	pixel.Id = board.pixelKey(msg.Id)
+	pixel.Channel = board.channel
 	pixel.Color = msg.Color
 
 	var existingPixel models.Pixel
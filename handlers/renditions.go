@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"Merry-Go/m3u8"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 업로드된 영상을 나누는 segment 길이 (초)
+const HLS_TIME = 10
+
+// 기본 화질 목록
+const defaultRenditions = "720p,480p,audio"
+
+// 변환하지 않고 원본 그대로 쓰는 화질 이름, 다른 화질과 함께 쓸 수 없음
+const SOURCE_RENDITION = "source"
+
+/*
+Rendition 업로드된 영상을 변환할 화질 하나
+
+Height가 0이면 영상 없이 오디오만 담고, Copy가 true이면 원본 코덱을 그대로 복사합니다.
+*/
+type Rendition struct {
+	Name         string
+	Copy         bool
+	Height       int    // 최대 세로 해상도, 원본이 더 작으면 원본 크기 유지
+	VideoBitrate string // ffmpeg -b:v 값
+	AudioBitrate string // ffmpeg -b:a 값
+	Bandwidth    int    // 마스터 플레이리스트의 BANDWIDTH
+	Codecs       string // 마스터 플레이리스트의 CODECS
+}
+
+// AudioOnly 오디오만 담는 화질인지 여부
+func (r Rendition) AudioOnly() bool {
+	return r.Height == 0 && !r.Copy
+}
+
+// HLS_RENDITIONS 환경 변수로 고를 수 있는 화질 목록
+var renditionPresets = map[string]Rendition{
+	"1080p": {Name: "1080p", Height: 1080, VideoBitrate: "5000k", AudioBitrate: "192k", Bandwidth: 5500000, Codecs: "avc1.4d4028,mp4a.40.2"},
+	"720p":  {Name: "720p", Height: 720, VideoBitrate: "2800k", AudioBitrate: "128k", Bandwidth: 3100000, Codecs: "avc1.4d401f,mp4a.40.2"},
+	"480p":  {Name: "480p", Height: 480, VideoBitrate: "1400k", AudioBitrate: "128k", Bandwidth: 1600000, Codecs: "avc1.4d401e,mp4a.40.2"},
+	"360p":  {Name: "360p", Height: 360, VideoBitrate: "800k", AudioBitrate: "96k", Bandwidth: 950000, Codecs: "avc1.4d401e,mp4a.40.2"},
+	"audio": {Name: "audio", AudioBitrate: "128k", Bandwidth: 140000, Codecs: "mp4a.40.2"},
+	// 원본 코덱을 알 수 없으므로 CODECS는 비워둠
+	SOURCE_RENDITION: {Name: SOURCE_RENDITION, Copy: true, Bandwidth: 5000000},
+}
+
+/*
+renditions 업로드된 영상을 변환할 화질 목록, 높은 화질부터 순서대로
+
+모든 화질의 플레이리스트는 같은 윈도우를 공유하여 함께 회전합니다.
+*/
+var renditions = loadRenditions()
+
+// loadRenditions HLS_RENDITIONS 환경 변수에서 화질 목록을 읽어옵니다. ex) 1080p,720p,480p,audio
+func loadRenditions() []Rendition {
+	value, exists := os.LookupEnv("HLS_RENDITIONS")
+	if !exists || strings.TrimSpace(value) == "" {
+		value = defaultRenditions
+	}
+
+	var result []Rendition
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		rendition, ok := renditionPresets[name]
+		if !ok {
+			log.Printf("Unknown rendition in HLS_RENDITIONS: %s\n", name)
+			continue
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, rendition)
+	}
+
+	// 원본 복사는 keyframe 위치를 맞출 수 없어서 다른 화질과 segment 경계가 달라지므로 단독으로만 사용
+	for _, rendition := range result {
+		if rendition.Copy && len(result) > 1 {
+			log.Printf("%s rendition cannot be combined with others, using %s only\n", SOURCE_RENDITION, SOURCE_RENDITION)
+			return []Rendition{rendition}
+		}
+	}
+
+	if len(result) == 0 {
+		log.Printf("No valid rendition in HLS_RENDITIONS, using default: %s\n", defaultRenditions)
+		for _, name := range strings.Split(defaultRenditions, ",") {
+			result = append(result, renditionPresets[name])
+		}
+	}
+	return result
+}
+
+// renditionNames 화질 이름 목록
+func renditionNames() []string {
+	names := make([]string, len(renditions))
+	for i, rendition := range renditions {
+		names[i] = rendition.Name
+	}
+	return names
+}
+
+// renditionPlaylistName 화질별 라이브 플레이리스트 파일 이름 ex) playlist_720p.m3u8
+func renditionPlaylistName(name string) string {
+	return PLAYLIST + SPLITER + name + ".m3u8"
+}
+
+// masterPlaylist 화질별 라이브 플레이리스트를 묶는 마스터 플레이리스트
+func masterPlaylist() *m3u8.MasterPlaylist {
+	master := &m3u8.MasterPlaylist{Version: 3}
+	for _, rendition := range renditions {
+		master.Variants = append(master.Variants, m3u8.Variant{
+			URI:       renditionPlaylistName(rendition.Name),
+			Bandwidth: rendition.Bandwidth,
+			Codecs:    rendition.Codecs,
+			Name:      rendition.Name,
+		})
+	}
+	return master
+}
+
+/*
+hlsArgs 업로드된 영상을 모든 화질로 한 번에 변환하는 ffmpeg 인자를 만듭니다.
+
+화질마다 같은 시각에 keyframe을 강제하여 segment 경계가 모든 화질에서 같도록 하고,
+outputDir/<화질 이름>/index.m3u8, seg0.ts, seg1.ts ... 를 생성합니다.
+오디오가 없는 영상은 무음 오디오를 넣어서 모든 화질이 오디오를 가지도록 하고, clip 구간이 있다면 해당 구간만 변환합니다.
+audioFilter 가 있다면 모든 화질의 오디오에 적용하며, 이 경우 원본 복사 화질도 오디오는 다시 인코딩합니다.
+*/
+func hlsArgs(inputFilePath string, outputDir string, hasAudio bool, clip clipRange, audioFilter string) []string {
+	args := append([]string{"-y"}, ffmpegInputArgs(inputFilePath, clip)...)
+	audioInput := "0:a:0"
+	if !hasAudio {
+		args = append(args, "-f", "lavfi", "-i", "anullsrc=channel_layout=stereo:sample_rate=48000")
+		audioInput = "1:a:0"
+	}
+
+	var streamMap []string
+	videoIndex, audioIndex := 0, 0
+	for _, rendition := range renditions {
+		switch {
+		case rendition.Copy:
+			args = append(args, "-map", "0:v:0", fmt.Sprintf("-c:v:%d", videoIndex), "copy")
+		case !rendition.AudioOnly():
+			args = append(args, "-map", "0:v:0",
+				fmt.Sprintf("-c:v:%d", videoIndex), "libx264",
+				fmt.Sprintf("-filter:v:%d", videoIndex), fmt.Sprintf(`scale=-2:min(%d\,trunc(ih/2)*2)`, rendition.Height),
+				fmt.Sprintf("-b:v:%d", videoIndex), rendition.VideoBitrate,
+				fmt.Sprintf("-maxrate:v:%d", videoIndex), rendition.VideoBitrate,
+				fmt.Sprintf("-bufsize:v:%d", videoIndex), rendition.VideoBitrate)
+		}
+
+		args = append(args, "-map", audioInput)
+		if rendition.Copy && hasAudio && audioFilter == "" {
+			args = append(args, fmt.Sprintf("-c:a:%d", audioIndex), "copy")
+		} else {
+			args = append(args, fmt.Sprintf("-c:a:%d", audioIndex), "aac",
+				fmt.Sprintf("-ar:a:%d", audioIndex), "48000", fmt.Sprintf("-ac:a:%d", audioIndex), "2")
+			if rendition.AudioBitrate != "" {
+				args = append(args, fmt.Sprintf("-b:a:%d", audioIndex), rendition.AudioBitrate)
+			}
+			if hasAudio && audioFilter != "" {
+				args = append(args, fmt.Sprintf("-filter:a:%d", audioIndex), audioFilter)
+			}
+		}
+
+		if rendition.AudioOnly() {
+			streamMap = append(streamMap, fmt.Sprintf("a:%d,name:%s", audioIndex, rendition.Name))
+		} else {
+			streamMap = append(streamMap, fmt.Sprintf("v:%d,a:%d,name:%s", videoIndex, audioIndex, rendition.Name))
+			videoIndex++
+		}
+		audioIndex++
+	}
+
+	if videoIndex > 0 && !renditions[0].Copy {
+		args = append(args,
+			"-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", HLS_TIME), "-sc_threshold", "0")
+	}
+	if !hasAudio {
+		args = append(args, "-shortest")
+	}
+	args = append(args, ffmpegThreadArgs()...)
+
+	return append(args,
+		"-f", "hls", "-hls_time", fmt.Sprint(HLS_TIME), "-hls_list_size", "0", "-hls_playlist_type", "vod",
+		"-start_number", "0",
+		"-hls_segment_filename", filepath.Join(outputDir, "%v", SEGNAME+"%d.ts"),
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(outputDir, "%v", VIDEO_PLAYLIST+".m3u8"))
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	TUS_VERSION        = "1.0.0"
+	TUS_EXTENSIONS     = "creation,checksum,termination"
+	TUS_CHECKSUMS      = "sha1,md5,sha256"
+	MAX_UPLOAD_SIZE    = 512 * 1024 * 1024 // 이어 올리기 업로드 최대 크기
+	uploadExpiration   = 24 * time.Hour    // 끝나지 않은 업로드, 끝난 업로드의 정보를 보관하는 시간
+	statusChecksumFail = 460               // tus checksum 확장의 Checksum Mismatch
+)
+
+// muxResumableUpload 같은 업로드에 조각이 동시에 붙지 않도록 업로드 파일 변경을 직렬화합니다.
+var muxResumableUpload sync.Mutex
+
+// resumableUpload 이어 올리기 업로드 정보, 업로드 파일 옆에 <ID>.info 로 저장
+type resumableUpload struct {
+	Id        string    `json:"id"` // 업로드 ID이자 작업 ID, 영상 ID
+	Length    int64     `json:"length"`
+	Filename  string    `json:"filename"`
+	Uploader  string    `json:"uploader"`
+	Start     float64   `json:"start"` // 사용할 구간 (초), End 가 0 이면 끝까지
+	End       float64   `json:"end"`
+	Channel   string    `json:"channel"` // 영상을 태울 채널 이름
+	CreatedAt time.Time `json:"created_at"`
+	// 마지막 조각까지 받아서 작업 큐에 넣은 작업 ID, 비어있으면 아직 받는 중
+	// 마지막 응답을 받지 못한 클라이언트가 다시 확인할 수 있도록 정보 파일은 만료될 때까지 남겨둠
+	Job         string    `json:"job,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+func (u *resumableUpload) dataPath() string {
+	return filepath.Join(uploadDir, u.Id)
+}
+
+func (u *resumableUpload) infoPath() string {
+	return filepath.Join(uploadDir, u.Id+".info")
+}
+
+// completed 마지막 조각까지 받아서 작업 큐에 넣었는지 여부
+func (u *resumableUpload) completed() bool {
+	return u.Job != ""
+}
+
+// expired 끝나지 않은 업로드는 만든 뒤, 끝난 업로드는 끝난 뒤 uploadExpiration 이 지났는지 여부
+func (u *resumableUpload) expired() bool {
+	if u.completed() {
+		return time.Since(u.CompletedAt) > uploadExpiration
+	}
+	return time.Since(u.CreatedAt) > uploadExpiration
+}
+
+// offset 지금까지 받은 바이트 수, 끝난 업로드의 파일은 작업이 가져가므로 항상 Length
+func (u *resumableUpload) offset() (int64, error) {
+	if u.completed() {
+		return u.Length, nil
+	}
+	info, err := os.Stat(u.dataPath())
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// remove 업로드 파일과 정보 파일을 삭제합니다. 끝난 업로드의 파일은 작업이 삭제하므로 정보 파일만 삭제합니다.
+func (u *resumableUpload) remove() {
+	if !u.completed() {
+		_ = os.Remove(u.dataPath())
+	}
+	_ = os.Remove(u.infoPath())
+}
+
+// loadResumableUpload 저장된 업로드 정보를 읽어옵니다. ID가 UUID가 아니면 경로로 쓰지 않고 바로 에러를 리턴합니다.
+func loadResumableUpload(id string) (*resumableUpload, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(filepath.Join(uploadDir, id+".info"))
+	if err != nil {
+		return nil, err
+	}
+	var upload resumableUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// pruneResumableUploads 만료된 업로드를 삭제합니다. 호출하는 쪽에서 muxResumableUpload를 잡고 있어야 합니다.
+func pruneResumableUploads() {
+	files, err := os.ReadDir(uploadDir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		id, found := strings.CutSuffix(file.Name(), ".info")
+		if !found {
+			continue
+		}
+		upload, err := loadResumableUpload(id)
+		if err != nil {
+			log.Printf("[Upload] 읽을 수 없는 업로드 삭제: %s\n", id)
+			_ = os.Remove(filepath.Join(uploadDir, id))
+			_ = os.Remove(filepath.Join(uploadDir, file.Name()))
+		} else if upload.expired() {
+			log.Printf("[Upload] 만료된 업로드 삭제: %s\n", id)
+			upload.remove()
+		}
+	}
+}
+
+// parseUploadMetadata Upload-Metadata 헤더를 읽습니다. ex) filename d29ybGQ=,uploader Ym9i
+func parseUploadMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(value)
+	}
+	return metadata
+}
+
+// newChecksumHash Upload-Checksum 헤더의 알고리즘에 맞는 hash, 지원하지 않으면 nil
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New()
+	case "md5":
+		return md5.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// setTusHeaders 모든 tus 응답에 붙는 헤더
+func setTusHeaders(c *fiber.Ctx) {
+	c.Set("Tus-Resumable", TUS_VERSION)
+	c.Set("Cache-Control", "no-store")
+}
+
+// checkTusVersion 클라이언트의 Tus-Resumable 헤더가 지원하는 버전인지 확인합니다.
+func checkTusVersion(c *fiber.Ctx) bool {
+	if c.Get("Tus-Resumable") == TUS_VERSION {
+		return true
+	}
+	c.Set("Tus-Version", TUS_VERSION)
+	return false
+}
+
+/* ResumableOptionsHandler 서버가 지원하는 tus 버전과 확장 목록을 알려줍니다.
+ */
+func ResumableOptionsHandler(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	c.Set("Tus-Version", TUS_VERSION)
+	c.Set("Tus-Extension", TUS_EXTENSIONS)
+	c.Set("Tus-Max-Size", strconv.Itoa(MAX_UPLOAD_SIZE))
+	c.Set("Tus-Checksum-Algorithm", TUS_CHECKSUMS)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+/* ResumableCreateHandler Upload-Length 크기의 빈 업로드를 만들고 Location 헤더로 업로드 주소를 알려줍니다.
+ */
+func ResumableCreateHandler(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return c.SendStatus(fiber.StatusPreconditionFailed)
+	}
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	if ch.merryGo.IsFull() && !evictionPolicy.ReplaceOldest {
+		return c.Status(fiber.StatusBadRequest).SendString("Merry-Go is Full")
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid Upload-Length")
+	}
+	if length > MAX_UPLOAD_SIZE {
+		return c.Status(fiber.StatusRequestEntityTooLarge).SendString("Upload is too large")
+	}
+
+	metadata := parseUploadMetadata(c.Get("Upload-Metadata"))
+	start, err := parseSeconds(metadata["start"])
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid start")
+	}
+	end, err := parseSeconds(metadata["end"])
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid end")
+	}
+	upload := &resumableUpload{
+		Id:        uuid.New().String(),
+		Length:    length,
+		Filename:  metadata["filename"],
+		Uploader:  metadata["uploader"],
+		Start:     start,
+		End:       end,
+		Channel:   ch.Name,
+		CreatedAt: time.Now(),
+	}
+
+	muxResumableUpload.Lock()
+	defer muxResumableUpload.Unlock()
+
+	pruneResumableUploads()
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		log.Println("Failed to create upload directory: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to create upload")
+	}
+	if err := os.WriteFile(upload.dataPath(), nil, 0644); err != nil {
+		log.Println("Failed to create upload file: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to create upload")
+	}
+	info, _ := json.Marshal(upload)
+	if err := writeFileAtomic(upload.infoPath(), info); err != nil {
+		upload.remove()
+		log.Println("Failed to save upload info: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to create upload")
+	}
+
+	log.Printf("[Upload] %s 업로드 생성, %d bytes\n", upload.Id, upload.Length)
+	c.Location(c.BaseURL() + c.Path() + "/" + upload.Id)
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+/*
+ResumableHeadHandler 지금까지 받은 크기(Upload-Offset)를 알려줍니다. 클라이언트는 끊긴 뒤 이 위치부터 이어서 보냅니다.
+
+끝난 업로드는 Upload-Offset 이 Upload-Length 와 같고 Upload-Job 헤더로 작업 주소를 알려줍니다.
+*/
+func ResumableHeadHandler(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return c.SendStatus(fiber.StatusPreconditionFailed)
+	}
+
+	muxResumableUpload.Lock()
+	defer muxResumableUpload.Unlock()
+
+	upload, err := loadResumableUpload(c.Params("id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	offset, err := upload.offset()
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.completed() {
+		c.Set("Upload-Job", "/jobs/"+upload.Job)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+/*
+ResumablePatchHandler Upload-Offset 위치에 조각을 이어 붙입니다.
+
+Upload-Offset 이 지금까지 받은 크기와 다르면 409, Upload-Checksum 이 맞지 않으면 조각을 버리고 460을 응답합니다.
+마지막 조각까지 받으면 업로드 작업 큐에 넣어 기존 업로드와 같은 길이 확인, HLS 변환을 거칩니다.
+마지막 조각의 응답을 받지 못한 클라이언트가 같은 조각을 다시 보내면 작업을 다시 넣지 않고 기존 Upload-Job 을 응답합니다.
+*/
+func ResumablePatchHandler(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return c.SendStatus(fiber.StatusPreconditionFailed)
+	}
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return c.Status(fiber.StatusUnsupportedMediaType).SendString("Content-Type must be application/offset+octet-stream")
+	}
+
+	clientOffset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || clientOffset < 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid Upload-Offset")
+	}
+
+	chunk := c.Body()
+
+	// 조각의 checksum 확인
+	if checksum := c.Get("Upload-Checksum"); checksum != "" {
+		algorithm, encoded, _ := strings.Cut(checksum, " ")
+		hasher := newChecksumHash(algorithm)
+		if hasher == nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Unsupported checksum algorithm")
+		}
+		expected, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid Upload-Checksum")
+		}
+		hasher.Write(chunk)
+		if !bytes.Equal(hasher.Sum(nil), expected) {
+			return c.Status(statusChecksumFail).SendString("Checksum Mismatch")
+		}
+	}
+
+	muxResumableUpload.Lock()
+	defer muxResumableUpload.Unlock()
+
+	upload, err := loadResumableUpload(c.Params("id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if upload.completed() && clientOffset+int64(len(chunk)) == upload.Length {
+		c.Set("Upload-Offset", strconv.FormatInt(upload.Length, 10))
+		c.Set("Upload-Job", "/jobs/"+upload.Job)
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+	offset, err := upload.offset()
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if clientOffset != offset {
+		c.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		return c.Status(fiber.StatusConflict).SendString("Upload-Offset does not match")
+	}
+	if offset+int64(len(chunk)) > upload.Length {
+		return c.Status(fiber.StatusRequestEntityTooLarge).SendString("Chunk exceeds Upload-Length")
+	}
+
+	err = appendChunk(upload.dataPath(), chunk)
+	if err != nil {
+		log.Println("Failed to append upload chunk: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save chunk")
+	}
+	offset += int64(len(chunk))
+	c.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	if offset < upload.Length {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	// 모두 받았으면 업로드 작업으로 넘김, 업로드 파일은 작업이 끝난 뒤 삭제됨
+	log.Printf("[Upload] %s 업로드 완료\n", upload.Id)
+	now := time.Now()
+	job := &UploadJob{
+		Id:           upload.Id,
+		Status:       JOB_QUEUED,
+		Uploader:     upload.Uploader,
+		OriginalName: upload.Filename,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		channel:      upload.Channel,
+		filePath:     upload.dataPath(),
+		trimStart:    upload.Start,
+		trimEnd:      upload.End,
+	}
+	if !enqueueJob(job) {
+		upload.remove()
+		return c.Status(fiber.StatusServiceUnavailable).SendString("Upload queue is full")
+	}
+
+	// 같은 조각을 다시 받았을 때 작업을 다시 넣지 않도록 끝난 업로드로 표시
+	upload.Job = job.Id
+	upload.CompletedAt = now
+	info, _ := json.Marshal(upload)
+	if err := writeFileAtomic(upload.infoPath(), info); err != nil {
+		log.Println("Failed to save upload info: ", err)
+	}
+	c.Set("Upload-Job", "/jobs/"+job.Id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+/* ResumableDeleteHandler 끝나지 않은 업로드를 취소하고 받은 조각을 삭제합니다. 끝난 업로드는 정보만 삭제하고 작업은 그대로 진행됩니다.
+ */
+func ResumableDeleteHandler(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return c.SendStatus(fiber.StatusPreconditionFailed)
+	}
+
+	muxResumableUpload.Lock()
+	defer muxResumableUpload.Unlock()
+
+	upload, err := loadResumableUpload(c.Params("id"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	upload.remove()
+	log.Printf("[Upload] %s 업로드 취소\n", upload.Id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// appendChunk 업로드 파일 끝에 조각을 붙이고 디스크에 기록될 때까지 기다립니다.
+func appendChunk(path string, chunk []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, writeErr := file.Write(chunk)
+	syncErr := file.Sync()
+	closeErr := file.Close()
+	return errors.Join(writeErr, syncErr, closeErr)
+}
@@ -1,201 +1,169 @@
 package handlers
 
 import (
-	"fmt"
-	strings2 "github.com/savsgio/gotils/strings"
+	"Merry-Go/data_struct"
 	"log"
-	"os"
-	"path/filepath"
-	"strings"
-	"sync"
 	"time"
 )
 
-var muxRotateVideo sync.Mutex
-var err error
+// Merry-Go가 비어있을 때 확인하는 주기
+const idleInterval = 10 * time.Second
 
-// 주기 변경을 위한 구조체
-type ChangeInterval struct {
-	Interval time.Duration
+// 업로드 후 유지 시간이 지난 영상을 확인하는 최대 주기
+const expiryCheckInterval = time.Minute
+
+// wakeRotation 회전 고루틴에게 윈도우를 다시 채우도록 알립니다. 이미 알림이 대기 중이면 무시합니다.
+func (ch *Channel) wakeRotation() {
+	select {
+	case ch.wake <- struct{}{}:
+	default:
+	}
+}
+
+// rescheduleRotation 회전 고루틴에게 다음 회전 시각을 다시 계산하도록 알립니다. 이미 알림이 대기 중이면 무시합니다.
+func (ch *Channel) rescheduleRotation() {
+	select {
+	case ch.reschedule <- struct{}{}:
+	default:
+	}
 }
 
-func RotateInteval() {
-	// 초기 주기 설정 (10초)
-	initialInterval := 2 * time.Second
-	newLength := 0
-	changeIntervalChan := make(chan ChangeInterval)
-	quit := make(chan struct{})
-	ticker := time.NewTicker(initialInterval)
-	defer ticker.Stop()
+/*
+rotateLoop 지금 재생 중인 segment가 끝나는 시각까지 기다렸다가 플레이리스트를 한 segment씩 앞으로 이동시킵니다.
+
+기다리는 시간은 #EXTINF 길이로 계산한 재생 종료 시각 기준이므로 타이머가 늦게 깨어나도 다음 회전에서 따라잡습니다.
+MERRYGO_TTL 이 설정되어 있다면 회전과 관계없이 주기적으로 만료된 영상을 제거합니다.
+채널이 삭제되면 종료합니다.
+*/
+func (ch *Channel) rotateLoop() {
+	interval, _ := ch.fillPlaylist()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	// 유지 시간 제한이 없으면 nil 채널이라 선택되지 않음
+	var expiry <-chan time.Time
+	if evictionPolicy.TTL > 0 {
+		ticker := time.NewTicker(min(evictionPolicy.TTL, expiryCheckInterval))
+		defer ticker.Stop()
+		expiry = ticker.C
+	}
 
 	for {
 		select {
-		case <-ticker.C:
-			newLength, err = RotateVideo(changeIntervalChan)
+		case <-timer.C:
+			next, err := ch.RotateVideo()
 			if err != nil {
 				log.Println(err)
 			}
-			ticker.Stop()
-			ticker = time.NewTicker(time.Duration(newLength) * time.Second)
-		case <-quit:
-			log.Println("종료 시그널 받음")
-			ticker.Stop()
+			timer.Reset(next)
+		case <-ch.wake:
+			// 윈도우가 비어서 쉬고 있던 경우에만 바로 재생을 시작
+			next, started := ch.fillPlaylist()
+			if started {
+				timer.Reset(next)
+			}
+		case <-ch.reschedule:
+			timer.Reset(ch.nextRotationInterval())
+		case <-expiry:
+			ch.expireVideos()
+		case <-ch.stop:
 			return
 		}
 	}
 }
 
-// RotateVideo rotate hls playlist from head to tail
-func RotateVideo(changeIntervalChan chan<- ChangeInterval) (int, error) {
-	if merryGo.IsEmpty() || merryGo.Count == 1 {
-		return 10, nil
-	}
+/*
+RotateVideo 맨 앞 segment의 재생이 끝났을 때 호출되며, 윈도우를 한 segment 앞으로 이동시키고 빈 자리를 Merry-Go의 다음 영상으로 채웁니다.
+segment 파일은 그대로 두고 플레이리스트의 sequence 값만 증가합니다.
 
-	muxRotateVideo.Lock()
-	defer muxRotateVideo.Unlock()
+return: 다음 호출까지 기다릴 시간 time.Duration, 에러 error
+*/
+func (ch *Channel) RotateVideo() (time.Duration, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
 
-	// Read the main playlist content
-	mainPlaylist, err := os.ReadFile(mainPlaylistFile)
-	if err != nil {
-		log.Fatalf("Error reading file: %v", err)
-		return 10, err
-	}
-	rawMainLines := strings.Split(string(mainPlaylist), "\n")
-
-	// Remove the #EXT-X-ENDLIST tag
-	// #EXT-X-ENDLIST tag 는 플레이 리스트가 끝나는 지점을 의미
-	var mainLines []string
-	for _, line := range rawMainLines {
-		trimmedLine := strings.TrimSpace(line)
-		if trimmedLine != "" && trimmedLine != "#EXT-X-ENDLIST" {
-			mainLines = append(mainLines, line)
-		}
+	// 멈춘 동안이나 삭제된 뒤에는 윈도우를 그대로 둠
+	if ch.paused || ch.deleted {
+		return idleInterval, nil
 	}
 
-	headStart, headEnd, headLength := merryGo.Head.Rider.Info()
-	lastSegNum := 0
-	mainLines, lastSegNum, err = rotatePlayList(mainLines, headStart, headEnd)
-	if err != nil {
-		return headLength, err
+	videoID, finished := ch.live.Advance()
+	if finished {
+		ch.finishVideo(videoID)
 	}
+	return ch.refillPlaylist()
+}
 
-	headStart, headEnd, err = rotateSegment(lastSegNum, headStart, headEnd)
-	if err != nil {
-		// TODO -- 에러가 날 경우를 대비해서 임시 파일에서 작업 -> 모든 작업 정상적으로 동작 -> 원본 파일 수정 -> 임시 파일 삭제 의 로직을 넣어야 제대로 된 멱등성이 보장됨
-		log.Println(err)
-		return headLength, err
+// finishVideo 재생이 끝난 영상의 재생 횟수 증가 후 만료된 영상을 제거합니다. 호출하는 쪽에서 mu를 잡고 있어야 합니다.
+func (ch *Channel) finishVideo(videoID string) {
+	if rider, _, ok := ch.merryGo.Find(func(s *data_struct.Segment) bool { return s.ID() == videoID }); ok {
+		rider.Played()
+		persistPlayed(rider)
 	}
-	merryGo.Head.Rider.Update(headStart, headEnd)
-	headStart, headEnd, headLength = merryGo.Head.Rider.Info()
-
-	_ = merryGo.Rotate()
-
-	headStart, headEnd, headLength = merryGo.Head.Rider.Info()
-
-	// Update Sequence (첫번째로 읽어올 Segment 파일 번호)
-	updateDurationTag(mainLines)
-	updateSequenceTag(mainLines, headStart)
-	// Write the combined lines back to the main playlist
-	err = os.WriteFile(mainPlaylistFile, []byte(strings.Join(mainLines, "\n")), 0644)
+	err := ch.evictExpired()
 	if err != nil {
-		return headLength, err
+		log.Println(err)
 	}
-
-	return headLength, nil
 }
 
 /*
-rotatePlayList: hls 플레이리스트 내의 순서를 rotate에 맞게 변경하는 함수
+refillPlaylist 윈도우에서 빠진 영상의 파일을 정리하고 빈 자리를 채운 뒤 플레이리스트와 Merry-Go 상태를 저장합니다.
 
-PlayListLines: 원본 플레이리스트
-start: 변경할 segment 넘버 시작
-end: 변경할 segment 넘버 끝
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
 
-return: 변경된 플레이리스트 []string, 변경 전 getLastSegmentNum() 결과 int, 에러 error
+return: 다음 회전까지 기다릴 시간 time.Duration, 에러 error
 */
-func rotatePlayList(PlayListLines []string, start int, end int) ([]string, int, error) {
-	tmpLines := make([]string, len(PlayListLines))
-	for i, v := range PlayListLines {
-		tmpLines[i] = v
-	}
-	startSegLine := fmt.Sprintf(SEGNAME+"%d.ts", start)
-	endSegLine := fmt.Sprintf(SEGNAME+"%d.ts", end)
-	startIndex := 0
-	endIndex := 0
-
-	lastSegNum, err := getLastSegmentNum()
-	if err != nil {
-		return nil, 0, err
-	}
-	//lastSegNum := start
-	beforeSegNum := lastSegNum
-
-	if start == end {
-		for i, v := range tmpLines {
-			if v == startSegLine {
-				startIndex = i - 1
-				endIndex = i
-			}
-		}
-	} else {
-		for i, v := range tmpLines {
-			if v == startSegLine {
-				startIndex = i - 1
+func (ch *Channel) refillPlaylist() (time.Duration, error) {
+	ch.removePendingVideos()
 
-			} else if v == endSegLine {
-				endIndex = i
-			}
-		}
-	}
+	ch.live.Fill()
+	err := ch.writePlaylist()
+	ch.persistMerryGo()
 
-	for i := startIndex + 1; i <= endIndex; i += 2 {
-		tmpLines[i] = fmt.Sprintf(SEGNAME+"%d.ts", lastSegNum)
-		PlayListLines[i] = tmpLines[i]
-		lastSegNum++
+	interval, ok := ch.untilNextRotation()
+	if !ok {
+		return idleInterval, err
 	}
+	return interval, err
+}
 
-	// 구분자 태그가 가장 처음에 위치한 상태로 초기화
-	subSlice := []string{"#EXT-X-DISCONTINUITY"}
-	// 슬라이스에서 start 인덱스부터 end 인덱스까지의 데이터 추출
-	subSlice = append(subSlice, tmpLines[startIndex:endIndex+1]...) // end 인덱스는 포함되지 않으므로 endIndex + 1으로 지정
+// nextRotationInterval 다음 회전까지 기다릴 시간, 멈춰 있거나 윈도우가 비어있으면 idleInterval
+func (ch *Channel) nextRotationInterval() time.Duration {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
 
-	// 슬라이스에서 해당 구간을 제거
-	// PlayListLines[endIndex+2:] 인 이유는 각 세그먼트들 사이에 #EXT-X-DISCONTINUITY 가 구분자 태그로 삽입되어있기 때문
-	PlayListLines = append(PlayListLines[:startIndex], PlayListLines[endIndex+2:]...)
+	interval, ok := ch.untilNextRotation()
+	if ch.paused || !ok {
+		return idleInterval
+	}
+	return interval
+}
 
-	// 추출한 데이터를 원래 슬라이스의 맨 뒤에 붙이기
-	PlayListLines = append(PlayListLines, subSlice...)
+/*
+fillPlaylist 윈도우를 앞으로 이동시키지 않고 빈 자리만 Merry-Go의 영상으로 채웁니다.
 
-	return PlayListLines, beforeSegNum, nil
-}
+return: 맨 앞 segment가 끝날 때까지 남은 시간 time.Duration, 비어있던 윈도우에 영상이 새로 들어왔는지 여부 bool
+*/
+func (ch *Channel) fillPlaylist() (time.Duration, bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
 
-func rotateSegment(lastSegNum int, start int, end int) (int, int, error) {
-	// 소스 디렉토리 내의 모든 파일 읽기
-	files, err := os.ReadDir(absHlsDir)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read source directory: %w", err)
+	// 멈춘 동안이나 삭제된 뒤에는 새로 재생을 시작하지 않음
+	if ch.paused || ch.deleted {
+		return idleInterval, false
 	}
 
-	beforeSegs := make([]string, end-start+1)
-	for i := start; i <= end; i++ {
-		beforeSegs = append(beforeSegs, fmt.Sprintf(SEGNAME+"%d.ts", i))
-	}
-	headStart := lastSegNum
-	// 세그먼트 파일 복사 -> 복사 없이 가능
-	for _, file := range files {
-		if strings2.Include(beforeSegs, file.Name()) {
-			newFileName := fmt.Sprintf(SEGNAME+"%d.ts", lastSegNum)
-			lastSegNum++
-			sourceFilePath := filepath.Join(absHlsDir, file.Name())
-			destFilePath := filepath.Join(absHlsDir, newFileName)
-			err := os.Rename(sourceFilePath, destFilePath)
-			if err != nil {
-				// TODO -- 변경 중에 에러가 날 경우 트랙잭션 개념으로 전체가 취소가 되는 예외처리가 이뤄져야 함
-				return 0, 0, fmt.Errorf("failed to rename file %s to %s: %w", sourceFilePath, destFilePath, err)
-			}
-			log.Printf("[Rotate] renamed %s to %s\n", sourceFilePath, destFilePath)
+	wasEmpty := ch.live.Len() == 0
+	if ch.live.Fill() > 0 {
+		if err := ch.writePlaylist(); err != nil {
+			log.Println(err)
 		}
+		ch.persistMerryGo()
+	}
+
+	interval, ok := ch.untilNextRotation()
+	if !ok {
+		return idleInterval, false
 	}
-	headEnd := lastSegNum - 1
-	return headStart, headEnd, nil
+	return interval, wasEmpty
 }
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 영상 위치 변경 요청
+type MoveRequest struct {
+	Id       string `json:"id"`
+	Position int    `json:"position"` // Head = 0, 영상 수 이상이면 맨 뒤
+}
+
+// 영상 고정 요청
+type PinRequest struct {
+	Id string `json:"id"`
+}
+
+// 회전 방식 변경 요청
+type StrategyRequest struct {
+	Strategy string `json:"strategy"`
+}
+
+/* MoveHandler 영상을 채널 Merry-Go의 해당 위치로 옮깁니다. 지금 재생 중인 영상 뒤의 윈도우는 바뀐 순서로 다시 채웁니다.
+ */
+func MoveHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	var req MoveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+	if req.Position < 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("Position must not be negative")
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	// 위치는 윈도우에서 되돌린 영상까지 포함한 순서 기준
+	ch.requeueWindow()
+	err = ch.merryGo.MoveTo(func(s *data_struct.Segment) bool { return s.ID() == req.Id }, req.Position)
+	if err != nil {
+		if errors.Is(err, data_struct.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Video not found")
+		}
+		log.Println("Failed to move video: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to move video")
+	}
+	log.Printf("[Move] %s 영상을 %d 번째로 이동\n", req.Id, req.Position)
+	if err := ch.rebuildPlaylist(); err != nil {
+		log.Println("Failed to write playlist: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to write playlist")
+	}
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/* PinHandler 영상을 고정하여 고정을 풀 때까지 항상 다음 영상으로 재생되도록 합니다. 고정할 수 있는 영상은 하나입니다.
+ */
+func PinHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	var req PinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.requeueWindow()
+	err = ch.merryGo.MoveToFront(func(s *data_struct.Segment) bool { return s.ID() == req.Id })
+	if err != nil {
+		if errors.Is(err, data_struct.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Video not found")
+		}
+		log.Println("Failed to pin video: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to pin video")
+	}
+	ch.setPinned(req.Id)
+	log.Printf("[Pin] %s 영상 고정\n", req.Id)
+	if err := ch.rebuildPlaylist(); err != nil {
+		log.Println("Failed to write playlist: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to write playlist")
+	}
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/* UnpinHandler 영상 고정을 풉니다.
+ */
+func UnpinHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.pinnedID != "" {
+		log.Printf("[Pin] %s 영상 고정 해제\n", ch.pinnedID)
+		ch.setPinned("")
+		ch.notifyMerryGoChanged()
+	}
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/*
+requeueWindow 윈도우에서 아직 재생을 시작하지 않은 영상을 빼고, 꺼내기 전처럼 Merry-Go의 Head 쪽으로 되돌립니다.
+삭제되었거나 슬레이트처럼 Merry-Go에 없는 영상은 되돌리지 않습니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) requeueWindow() {
+	dropped := ch.live.DropQueued()
+	// 꺼낸 순서의 반대로 Head에 넣어야 꺼내기 전 순서가 됨
+	for i := len(dropped) - 1; i >= 0; i-- {
+		id := dropped[i]
+		err := ch.merryGo.MoveToFront(func(s *data_struct.Segment) bool { return s.ID() == id })
+		if err != nil && !errors.Is(err, data_struct.ErrNotFound) {
+			log.Printf("Failed to requeue video %s: %v\n", id, err)
+		}
+	}
+	front, ok := ch.live.Front()
+	ch.lastWasSlate = ok && front.VideoID == SLATE_ID
+}
+
+/*
+rebuildPlaylist requeueWindow 로 비운 윈도우를 바뀐 순서로 다시 채워서 플레이리스트를 씁니다.
+멈춘 상태에서도 바로 반영하며, 윈도우가 비어있다가 채워진 경우를 위해 회전 타이머를 다시 맞춥니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) rebuildPlaylist() error {
+	_, err := ch.refillPlaylist()
+	ch.rescheduleRotation()
+	return err
+}
+
+/* SkipHandler 지금 재생 중인 영상을 바로 끝내고 다음 영상으로 넘어갑니다. 건너뛴 영상도 재생한 것으로 셉니다.
+ */
+func SkipHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	videoID, ok := ch.live.Skip()
+	if !ok {
+		return c.Status(fiber.StatusConflict).SendString("Nothing is playing")
+	}
+	log.Printf("[Skip] %s 영상 건너뜀\n", videoID)
+	ch.finishVideo(videoID)
+
+	// 멈춘 상태에서도 건너뛴 결과는 플레이리스트에 반영
+	_, err = ch.refillPlaylist()
+	if err != nil {
+		log.Println("Failed to write playlist: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to write playlist")
+	}
+	ch.rescheduleRotation()
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/* PauseHandler 회전을 멈춥니다. 플레이리스트가 더 이상 앞으로 이동하지 않습니다.
+ */
+func PauseHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if !ch.paused {
+		ch.paused = true
+		log.Println("[Pause] 회전 멈춤")
+		ch.rescheduleRotation()
+		ch.notifyMerryGoChanged()
+	}
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/* ResumeHandler 멈춘 회전을 다시 시작합니다. 맨 앞 segment는 지금부터 다시 재생되는 것으로 계산합니다.
+ */
+func ResumeHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.paused {
+		ch.paused = false
+		ch.live.ResetClock()
+		log.Println("[Resume] 회전 다시 시작")
+		_, err = ch.refillPlaylist()
+		if err != nil {
+			log.Println("Failed to write playlist: ", err)
+		}
+		ch.rescheduleRotation()
+	}
+
+	return c.JSON(ch.merryGoStatus())
+}
+
+/* StrategyHandler 다음 영상을 고르는 회전 방식을 변경합니다. 이미 플레이리스트 윈도우에 들어간 영상은 그대로 재생됩니다.
+ */
+func StrategyHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	var req StrategyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+	strategy, ok := newRotationStrategy(req.Strategy)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).SendString("Unknown strategy: " + req.Strategy)
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.strategy = strategy
+	log.Printf("[Strategy] 회전 방식 변경: %s\n", strategy.Name())
+	ch.saveSettings()
+	ch.notifyMerryGoChanged()
+
+	return c.JSON(ch.merryGoStatus())
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+// 회전 방식 이름
+const (
+	STRATEGY_ROUND_ROBIN = "round-robin" // Head부터 순서대로
+	STRATEGY_SHUFFLE     = "shuffle"     // 한 바퀴마다 순서를 섞음
+	STRATEGY_LIKES       = "likes"       // 좋아요가 많을수록 자주
+	STRATEGY_RECENT      = "recent"      // 최근에 올라온 영상일수록 자주
+	STRATEGY_FRESH       = "fresh"       // 새로 올라온 영상은 다른 영상 사이사이에 추가로 재생
+)
+
+const defaultStrategy = STRATEGY_ROUND_ROBIN
+
+const (
+	recentHalfLife   = 24 * time.Hour // recent 방식에서 가중치가 절반이 되는 시간
+	minRecencyWeight = 0.05           // 오래된 영상도 가끔은 재생되도록 하는 최소 가중치
+	freshExtraPlays  = 3              // fresh 방식에서 새 영상을 추가로 재생하는 횟수
+)
+
+/*
+RotationStrategy 플레이리스트 윈도우에 다음으로 들어갈 영상을 고르는 방식
+
+모든 방식은 Merry-Go ring 위에서 동작합니다. 고른 영상을 Head로 옮긴 뒤 Peek, Rotate 하므로 나머지 영상의 순서는 유지되고,
+/api/merrygo 의 순서는 round-robin 기준의 다음 순서를 보여줍니다.
+eligible 이 있다면 eligible 을 만족하는 영상 중에서만 고르며, nil 이면 모든 영상이 대상입니다.
+
+호출하는 쪽에서 채널의 mu를 잡고 있어야 합니다.
+*/
+type RotationStrategy interface {
+	Name() string
+	Next(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) (*data_struct.Segment, bool)
+}
+
+/*
+newRotationStrategy 이름에 해당하는 회전 방식을 생성합니다. 상태를 가지는 방식이 있으므로 Merry-Go마다 따로 생성해야 합니다.
+
+return: 회전 방식 RotationStrategy, 알 수 없는 이름이면 false
+*/
+func newRotationStrategy(name string) (RotationStrategy, bool) {
+	switch name {
+	case STRATEGY_ROUND_ROBIN:
+		return roundRobin{}, true
+	case STRATEGY_SHUFFLE:
+		return &shufflePerLap{}, true
+	case STRATEGY_LIKES:
+		return weighted{name: STRATEGY_LIKES, weight: likesWeight}, true
+	case STRATEGY_RECENT:
+		return weighted{name: STRATEGY_RECENT, weight: recencyWeight}, true
+	case STRATEGY_FRESH:
+		return &freshBoost{boosted: map[string]int{}, since: time.Now()}, true
+	}
+	return nil, false
+}
+
+// loadRotationStrategy ROTATION_STRATEGY 환경 변수에서 회전 방식을 읽어옵니다.
+func loadRotationStrategy() RotationStrategy {
+	name := defaultStrategy
+	if value, exists := os.LookupEnv("ROTATION_STRATEGY"); exists {
+		name = strings.TrimSpace(value)
+	}
+	strategy, ok := newRotationStrategy(name)
+	if !ok {
+		log.Printf("Error parsing ROTATION_STRATEGY: %s\n", name)
+		strategy, _ = newRotationStrategy(defaultStrategy)
+	}
+	return strategy
+}
+
+// eligibleRiders Head부터 순서대로 eligible 을 만족하는 영상, eligible 이 nil 이면 모든 영상
+func eligibleRiders(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) []*data_struct.Segment {
+	riders := []*data_struct.Segment{}
+	for rider := range ring.Values() {
+		if eligible == nil || eligible(rider) {
+			riders = append(riders, rider)
+		}
+	}
+	return riders
+}
+
+// takeHead Head 영상을 꺼내고 Merry-Go를 회전시킵니다.
+func takeHead(ring *data_struct.MerryGo[*data_struct.Segment]) (*data_struct.Segment, bool) {
+	head, err := ring.Peek()
+	if err != nil {
+		return nil, false
+	}
+	_ = ring.Rotate()
+	return head, true
+}
+
+// takeRider 해당 영상을 Head로 옮긴 뒤 꺼내고 Merry-Go를 회전시킵니다.
+func takeRider(ring *data_struct.MerryGo[*data_struct.Segment], rider *data_struct.Segment) (*data_struct.Segment, bool) {
+	_ = ring.MoveToFront(func(s *data_struct.Segment) bool { return s == rider })
+	return takeHead(ring)
+}
+
+// roundRobin Head부터 순서대로 재생
+type roundRobin struct{}
+
+func (roundRobin) Name() string {
+	return STRATEGY_ROUND_ROBIN
+}
+
+func (roundRobin) Next(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) (*data_struct.Segment, bool) {
+	riders := eligibleRiders(ring, eligible)
+	if len(riders) == 0 {
+		return nil, false
+	}
+	return takeRider(ring, riders[0])
+}
+
+// shufflePerLap 한 바퀴를 돌 때마다 순서를 섞어서, 모든 영상이 한 바퀴에 한 번씩 무작위 순서로 재생
+type shufflePerLap struct {
+	remaining int // 이번 바퀴에 남은 영상 수
+}
+
+func (s *shufflePerLap) Name() string {
+	return STRATEGY_SHUFFLE
+}
+
+func (s *shufflePerLap) Next(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) (*data_struct.Segment, bool) {
+	riders := eligibleRiders(ring, eligible)
+	if len(riders) == 0 {
+		return nil, false
+	}
+	// 영상이 빠지거나 대상이 바뀌어서 남은 수가 실제보다 많아진 경우도 바퀴가 끝난 것으로 봄
+	if s.remaining <= 0 || s.remaining > len(riders) {
+		ring.Shuffle()
+		riders = eligibleRiders(ring, eligible)
+		s.remaining = len(riders)
+	}
+	s.remaining--
+	return takeRider(ring, riders[0])
+}
+
+// weighted 가중치에 비례하는 확률로 다음 영상을 고름
+type weighted struct {
+	name   string
+	weight func(*data_struct.Segment) float64
+}
+
+func (w weighted) Name() string {
+	return w.name
+}
+
+func (w weighted) Next(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) (*data_struct.Segment, bool) {
+	riders := eligibleRiders(ring, eligible)
+	if len(riders) == 0 {
+		return nil, false
+	}
+
+	weights := make([]float64, len(riders))
+	total := 0.0
+	for i, rider := range riders {
+		weights[i] = w.weight(rider)
+		total += weights[i]
+	}
+
+	pick := rand.Float64() * total
+	for i, rider := range riders {
+		pick -= weights[i]
+		if pick < 0 {
+			return takeRider(ring, rider)
+		}
+	}
+	return takeRider(ring, riders[0])
+}
+
+// likesWeight 좋아요 수 + 1, 좋아요가 없는 영상도 재생되도록 1을 더함
+func likesWeight(rider *data_struct.Segment) float64 {
+	return float64(rider.LikeCount() + 1)
+}
+
+// recencyWeight 업로드 후 recentHalfLife 가 지날 때마다 절반이 되는 가중치
+func recencyWeight(rider *data_struct.Segment) float64 {
+	age := time.Since(rider.UploadedAt())
+	return max(math.Pow(0.5, age.Hours()/recentHalfLife.Hours()), minRecencyWeight)
+}
+
+// freshBoost 순서대로 재생하되, 새로 올라온 영상은 freshExtraPlays 번까지 다른 영상 사이사이에 한 번씩 더 재생
+type freshBoost struct {
+	boosted     map[string]int // 영상별로 추가로 재생한 횟수
+	lastBoosted bool           // 직전에 추가 재생을 했는지 여부, 추가 재생이 연달아 나오지 않도록 함
+	since       time.Time      // 이 시각 이후에 올라온 영상만 새 영상으로 봄, 서버를 다시 시작해도 예전 영상이 다시 추가 재생되지 않도록 함
+}
+
+func (f *freshBoost) Name() string {
+	return STRATEGY_FRESH
+}
+
+func (f *freshBoost) Next(ring *data_struct.MerryGo[*data_struct.Segment], eligible func(*data_struct.Segment) bool) (*data_struct.Segment, bool) {
+	// 빠진 영상 정리
+	present := map[string]bool{}
+	for rider := range ring.Values() {
+		present[rider.ID()] = true
+	}
+	for id := range f.boosted {
+		if !present[id] {
+			delete(f.boosted, id)
+		}
+	}
+
+	riders := eligibleRiders(ring, eligible)
+	if len(riders) == 0 {
+		return nil, false
+	}
+	if !f.lastBoosted && len(riders) > 1 {
+		// 아직 추가 재생이 남은 영상 중 가장 최근에 올라온 영상
+		var fresh *data_struct.Segment
+		for _, rider := range riders {
+			if f.boosted[rider.ID()] >= freshExtraPlays || !rider.UploadedAt().After(f.since) {
+				continue
+			}
+			if fresh == nil || rider.UploadedAt().After(fresh.UploadedAt()) {
+				fresh = rider
+			}
+		}
+		if fresh != nil {
+			f.boosted[fresh.ID()]++
+			f.lastBoosted = true
+			return takeRider(ring, fresh)
+		}
+	}
+
+	f.lastBoosted = false
+	return takeRider(ring, riders[0])
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// SandboxLimits 업로드된 파일을 다루는 ffprobe/ffmpeg 프로세스의 자원 제한
+type SandboxLimits struct {
+	ProbeTimeout  time.Duration // ffprobe 실행 시간 제한
+	FfmpegTimeout time.Duration // ffmpeg 실행 시간 제한
+	MemoryMB      int           // 가상 메모리 제한 (MB), 0 이면 제한 없음
+	CPUSeconds    int           // CPU 사용 시간 제한 (초), 0 이면 제한 없음
+	Threads       int           // ffmpeg 인코딩 스레드 수, 0 이면 ffmpeg 기본값
+}
+
+var sandboxLimits = loadSandboxLimits()
+
+// prlimit 경로, 없으면 실행 시간 제한만 적용
+var prlimitPath, _ = exec.LookPath("prlimit")
+
+// loadSandboxLimits FFPROBE_TIMEOUT, FFMPEG_TIMEOUT, FFMPEG_MEMORY_LIMIT, FFMPEG_CPU_LIMIT, FFMPEG_THREADS 환경 변수에서 제한을 읽어옵니다.
+func loadSandboxLimits() SandboxLimits {
+	limits := SandboxLimits{
+		ProbeTimeout:  30 * time.Second,
+		FfmpegTimeout: 5 * time.Minute,
+		MemoryMB:      2048,
+		CPUSeconds:    600,
+		Threads:       2,
+	}
+
+	limits.ProbeTimeout = lookupDuration("FFPROBE_TIMEOUT", limits.ProbeTimeout, positive)
+	limits.FfmpegTimeout = lookupDuration("FFMPEG_TIMEOUT", limits.FfmpegTimeout, positive)
+	limits.MemoryMB = lookupNonNegativeInt("FFMPEG_MEMORY_LIMIT", limits.MemoryMB)
+	limits.CPUSeconds = lookupNonNegativeInt("FFMPEG_CPU_LIMIT", limits.CPUSeconds)
+	limits.Threads = lookupNonNegativeInt("FFMPEG_THREADS", limits.Threads)
+
+	if prlimitPath == "" && (limits.MemoryMB > 0 || limits.CPUSeconds > 0) {
+		log.Println("prlimit not found, ffmpeg memory/CPU limits are disabled")
+	}
+	return limits
+}
+
+/*
+sandboxCommand 업로드된 파일을 다루는 ffprobe/ffmpeg를 자원 제한을 걸어서 실행할 수 있도록 준비합니다.
+
+timeout 이 지나면 프로세스를 종료하고, prlimit 이 있다면 메모리와 CPU 사용 시간도 제한합니다.
+리턴된 cancel 은 실행이 끝난 뒤 반드시 호출해야 합니다.
+*/
+func sandboxCommand(timeout time.Duration, name string, args ...string) (*exec.Cmd, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	if prlimitPath != "" && (sandboxLimits.MemoryMB > 0 || sandboxLimits.CPUSeconds > 0) {
+		limitArgs := []string{}
+		if sandboxLimits.MemoryMB > 0 {
+			limitArgs = append(limitArgs, "--as="+strconv.Itoa(sandboxLimits.MemoryMB*1024*1024))
+		}
+		if sandboxLimits.CPUSeconds > 0 {
+			limitArgs = append(limitArgs, "--cpu="+strconv.Itoa(sandboxLimits.CPUSeconds))
+		}
+		limitArgs = append(limitArgs, "--", name)
+		return exec.CommandContext(ctx, prlimitPath, append(limitArgs, args...)...), cancel
+	}
+
+	return exec.CommandContext(ctx, name, args...), cancel
+}
+
+// probeCommand ffprobe를 자원 제한을 걸어서 실행할 수 있도록 준비합니다. 업로드된 파일 외의 프로토콜은 열지 않습니다.
+func probeCommand(args ...string) (*exec.Cmd, context.CancelFunc) {
+	args = append([]string{"-protocol_whitelist", "file"}, args...)
+	return sandboxCommand(sandboxLimits.ProbeTimeout, "ffprobe", args...)
+}
+
+// ffmpegInputArgs 업로드된 파일을 clip 구간만 ffmpeg 입력으로 넣는 인자, 업로드된 파일 외의 프로토콜은 열지 않습니다.
+func ffmpegInputArgs(inputFilePath string, clip clipRange) []string {
+	args := append([]string{"-protocol_whitelist", "file"}, clip.inputArgs()...)
+	return append(args, "-i", inputFilePath)
+}
+
+// ffmpegThreadArgs FFMPEG_THREADS 에 따른 인코딩 스레드 수 인자
+func ffmpegThreadArgs() []string {
+	if sandboxLimits.Threads == 0 {
+		return nil
+	}
+	return []string{"-threads", strconv.Itoa(sandboxLimits.Threads)}
+}
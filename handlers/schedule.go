@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"Merry-Go/database"
+	"Merry-Go/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 편성 시각을 계산하는 시간대
+var scheduleLocation = loadScheduleLocation()
+
+// loadScheduleLocation SCHEDULE_TIMEZONE 환경 변수에서 편성 시간대를 읽어옵니다. ex) Asia/Seoul
+func loadScheduleLocation() *time.Location {
+	return lookupValue("SCHEDULE_TIMEZONE", time.Local, time.LoadLocation, nil)
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+/*
+scheduleBlock 편성 블록 하나, 시작 시각부터 끝 시각 전까지 블록의 영상만 회전합니다.
+
+끝 시각이 시작 시각보다 이르면 다음 날 끝 시각까지 이어지며, 요일은 시작한 날 기준입니다. 시작과 끝이 같으면 하루 종일입니다.
+*/
+type scheduleBlock struct {
+	record models.Schedule
+	start  int                   // 시작 시각, 자정부터의 분
+	end    int                   // 끝 시각, 자정부터의 분
+	days   map[time.Weekday]bool // 비어있으면 매일
+	videos map[string]bool
+}
+
+// parseClock HH:MM 형식의 시각을 자정부터의 분으로 변환합니다.
+func parseClock(value string) (int, error) {
+	hour, minute, found := strings.Cut(value, ":")
+	if !found {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	m, err := strconv.Atoi(minute)
+	if err != nil || m < 0 || m > 59 || len(minute) != 2 {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	return h*60 + m, nil
+}
+
+// newScheduleBlock DB에 저장된 편성 블록을 읽습니다.
+func newScheduleBlock(record models.Schedule) (*scheduleBlock, error) {
+	block := &scheduleBlock{record: record, days: map[time.Weekday]bool{}, videos: map[string]bool{}}
+
+	var err error
+	if block.start, err = parseClock(record.Start); err != nil {
+		return nil, err
+	}
+	if block.end, err = parseClock(record.End); err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(record.Days, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		day, ok := weekdayNames[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid day: %s", name)
+		}
+		block.days[day] = true
+	}
+
+	var videoIDs []string
+	if err := json.Unmarshal([]byte(record.Videos), &videoIDs); err != nil {
+		return nil, fmt.Errorf("invalid videos of schedule %d: %w", record.Id, err)
+	}
+	for _, id := range videoIDs {
+		block.videos[id] = true
+	}
+	return block, nil
+}
+
+// activeAt 해당 시각에 블록이 편성되어 있는지 여부
+func (b *scheduleBlock) activeAt(at time.Time) bool {
+	at = at.In(scheduleLocation)
+	minute := at.Hour()*60 + at.Minute()
+	day := at.Weekday()
+
+	switch {
+	case b.start == b.end:
+	case b.start < b.end:
+		if minute < b.start || minute >= b.end {
+			return false
+		}
+	case minute >= b.start:
+	case minute < b.end:
+		// 전날 시작한 블록
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return len(b.days) == 0 || b.days[day]
+}
+
+// ScheduleEntry 편성표 API 응답의 블록 하나
+type ScheduleEntry struct {
+	Id     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Start  string   `json:"start"`
+	End    string   `json:"end"`
+	Days   []string `json:"days"`
+	Videos []string `json:"videos"`
+	Active bool     `json:"active"` // 지금 편성되어 있는지 여부
+}
+
+func (b *scheduleBlock) entry(now time.Time) ScheduleEntry {
+	entry := ScheduleEntry{Id: b.record.Id, Name: b.record.Name, Start: b.record.Start, End: b.record.End,
+		Days: []string{}, Videos: []string{}, Active: b.activeAt(now)}
+	for _, name := range strings.Split(b.record.Days, ",") {
+		if name != "" {
+			entry.Days = append(entry.Days, name)
+		}
+	}
+	_ = json.Unmarshal([]byte(b.record.Videos), &entry.Videos)
+	return entry
+}
+
+/*
+scheduledRiders 해당 시각에 회전할 영상을 고르는 조건과 편성된 블록 이름
+
+블록이 편성되어 있으면 블록의 영상만, 아니면 어느 블록에도 속하지 않은 영상만 회전합니다.
+블록이 여러 개 겹치면 먼저 만든 블록을 따르며, 편성이 없으면 nil 을 리턴하여 모든 영상이 대상이 됩니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) scheduledRiders(at time.Time) (func(*data_struct.Segment) bool, string) {
+	if len(ch.schedule) == 0 {
+		return nil, ""
+	}
+	for _, block := range ch.schedule {
+		if block.activeAt(at) {
+			return func(s *data_struct.Segment) bool { return block.videos[s.ID()] }, block.record.Name
+		}
+	}
+	return func(s *data_struct.Segment) bool {
+		for _, block := range ch.schedule {
+			if block.videos[s.ID()] {
+				return false
+			}
+		}
+		return true
+	}, ""
+}
+
+// loadSchedule DB에 저장된 채널의 편성 블록을 불러옵니다. 호출하는 쪽에서 mu를 잡고 있어야 합니다.
+func (ch *Channel) loadSchedule() error {
+	var records []models.Schedule
+	result := database.DB.Where("channel = ?", ch.Name).Order("id asc").Find(&records)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	ch.schedule = nil
+	for _, record := range records {
+		block, err := newScheduleBlock(record)
+		if err != nil {
+			log.Printf("[Schedule] %v\n", err)
+			continue
+		}
+		ch.schedule = append(ch.schedule, block)
+	}
+	return nil
+}
+
+/* ScheduleHandler 채널의 편성표를 만든 순서대로 리턴합니다.
+ */
+func ScheduleHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]ScheduleEntry, len(ch.schedule))
+	for i, block := range ch.schedule {
+		entries[i] = block.entry(now)
+	}
+	return c.JSON(entries)
+}
+
+// 편성 블록 추가 요청
+type ScheduleRequest struct {
+	Name   string   `json:"name"`
+	Start  string   `json:"start"`  // HH:MM
+	End    string   `json:"end"`    // HH:MM
+	Days   []string `json:"days"`   // sun, mon, tue, wed, thu, fri, sat, 비어있으면 매일
+	Videos []string `json:"videos"` // 블록에서 재생할 영상 ID
+}
+
+/*
+CreateScheduleHandler 편성 블록을 추가합니다. 블록의 영상은 채널 Merry-Go에 타고 있는 영상이어야 합니다.
+
+바뀐 편성은 다음에 플레이리스트에 들어갈 영상부터 적용되므로, 재생 중인 영상은 끝까지 재생됩니다.
+*/
+func CreateScheduleHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	var req ScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid request body")
+	}
+	if len(req.Videos) == 0 {
+		return c.Status(fiber.StatusBadRequest).SendString("Videos must not be empty")
+	}
+	for i, day := range req.Days {
+		req.Days[i] = strings.ToLower(strings.TrimSpace(day))
+	}
+	videos, _ := json.Marshal(req.Videos)
+	record := models.Schedule{
+		Channel: ch.Name,
+		Name:    req.Name,
+		Start:   req.Start,
+		End:     req.End,
+		Days:    strings.Join(req.Days, ","),
+		Videos:  string(videos),
+	}
+	block, err := newScheduleBlock(record)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	for _, id := range req.Videos {
+		if _, _, ok := ch.merryGo.Find(func(s *data_struct.Segment) bool { return s.ID() == id }); !ok {
+			return c.Status(fiber.StatusBadRequest).SendString("Video not found: " + id)
+		}
+	}
+	result := database.DB.Create(&record)
+	if result.Error != nil {
+		log.Println("Failed to save schedule: ", result.Error)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save schedule")
+	}
+	block.record = record
+	ch.schedule = append(ch.schedule, block)
+	sort.Slice(ch.schedule, func(i, j int) bool { return ch.schedule[i].record.Id < ch.schedule[j].record.Id })
+	log.Printf("[Schedule] %s 블록 추가 %s ~ %s\n", record.Name, record.Start, record.End)
+
+	return c.Status(fiber.StatusCreated).JSON(block.entry(time.Now()))
+}
+
+/* DeleteScheduleHandler 편성 블록을 삭제합니다. 블록의 영상은 Merry-Go에 그대로 남습니다.
+ */
+func DeleteScheduleHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid schedule id")
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	for i, block := range ch.schedule {
+		if uint64(block.record.Id) != id {
+			continue
+		}
+		result := database.DB.Delete(&models.Schedule{}, "id = ?", id)
+		if result.Error != nil {
+			log.Println("Failed to delete schedule: ", result.Error)
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to delete schedule")
+		}
+		ch.schedule = append(ch.schedule[:i], ch.schedule[i+1:]...)
+		log.Printf("[Schedule] %s 블록 삭제\n", block.record.Name)
+		return c.JSON(fiber.Map{"status": "success"})
+	}
+	return c.Status(fiber.StatusNotFound).SendString("Schedule not found")
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"Merry-Go/playlist"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SLATE_ID         = "slate"      // 슬레이트 영상 ID이자 hls 디렉토리 아래의 디렉토리 이름
+	slateStampFile   = "slate.json" // 슬레이트를 만든 설정, 설정이 같으면 다시 만들지 않음
+	defaultSlateText = "Upload a video!"
+)
+
+/*
+SlateConfig Merry-Go에 영상이 없거나 하나뿐일 때 대신 재생할 슬레이트 설정
+
+Video 가 있으면 운영자가 준비한 영상을, 없으면 Text 를 적은 카드를 Duration 길이로 만들어서 사용합니다.
+*/
+type SlateConfig struct {
+	Enabled  bool    `json:"-"`
+	Video    string  `json:"video"`
+	Text     string  `json:"text"`
+	Duration float64 `json:"duration"` // 카드 길이 (초)
+}
+
+var slateConfig = loadSlateConfig()
+
+// loadSlateConfig SLATE, SLATE_VIDEO, SLATE_TEXT, SLATE_DURATION 환경 변수에서 설정을 읽어옵니다.
+func loadSlateConfig() SlateConfig {
+	config := SlateConfig{Enabled: true, Text: defaultSlateText, Duration: 5}
+
+	config.Enabled = lookupBool("SLATE", config.Enabled)
+	if os.Getenv("SLATE_VIDEO") != "" {
+		config.Video = lookupValue("SLATE_VIDEO", "", filepath.Abs, nil)
+	}
+	if value, exists := os.LookupEnv("SLATE_TEXT"); exists {
+		config.Text = value
+	}
+	config.Duration = lookupFloat("SLATE_DURATION", config.Duration, func(duration float64) bool { return duration > 0 && duration <= HLS_TIME })
+	return config
+}
+
+// slateStamp 슬레이트를 만든 설정과 화질 목록, 바뀌었으면 다시 만듦
+type slateStamp struct {
+	Config        SlateConfig `json:"config"`
+	VideoModified time.Time   `json:"video_modified"` // 운영자가 준비한 영상의 수정 시각, 같은 경로의 파일을 바꾼 경우
+	Renditions    []string    `json:"renditions"`
+}
+
+// 준비된 슬레이트 영상, 준비되기 전이나 만들지 못했다면 nil
+var slate *playlist.Video
+var slateMu sync.RWMutex
+
+/*
+slateVideo 윈도우에 넣을 슬레이트 영상, 준비되지 않았으면 false
+
+segment 경로는 채널마다 플레이리스트 위치가 다르므로 /hls/slate/<화질>/seg0.ts 처럼 절대 경로를 사용합니다.
+*/
+func slateVideo() (playlist.Video, bool) {
+	slateMu.RLock()
+	defer slateMu.RUnlock()
+	if slate == nil {
+		return playlist.Video{}, false
+	}
+	return *slate, true
+}
+
+/*
+PrepareSlate 슬레이트를 hls 디렉토리의 slate 디렉토리에 HLS로 만들고, 모든 채널이 바로 사용할 수 있도록 회전 고루틴을 깨웁니다.
+
+이전에 같은 설정으로 만든 슬레이트가 있으면 다시 만들지 않습니다. 만들지 못하면 슬레이트 없이 동작합니다.
+*/
+func PrepareSlate() {
+	if !slateConfig.Enabled {
+		return
+	}
+
+	slateDir := filepath.Join(absHlsDir, SLATE_ID)
+	stampValue := slateStamp{Config: slateConfig, Renditions: renditionNames()}
+	if slateConfig.Video != "" {
+		info, err := os.Stat(slateConfig.Video)
+		if err != nil {
+			log.Printf("[Slate] 슬레이트 영상을 찾을 수 없습니다: %v\n", err)
+			return
+		}
+		stampValue.VideoModified = info.ModTime().UTC()
+	}
+	stamp, _ := json.Marshal(stampValue)
+	if existing, err := os.ReadFile(filepath.Join(slateDir, slateStampFile)); err != nil || !bytes.Equal(existing, stamp) {
+		if err := buildSlate(slateDir, stamp); err != nil {
+			log.Printf("[Slate] 슬레이트를 만들지 못했습니다: %v\n", err)
+			return
+		}
+	}
+
+	durations, err := readRenditionDurations(slateDir)
+	if err != nil {
+		log.Printf("[Slate] 슬레이트를 읽지 못했습니다: %v\n", err)
+		return
+	}
+	video := &playlist.Video{ID: SLATE_ID, Durations: durations, URIs: map[string][]string{}}
+	for _, rendition := range renditions {
+		for i := range durations {
+			video.URIs[rendition.Name] = append(video.URIs[rendition.Name],
+				path.Join("/hls", SLATE_ID, rendition.Name, fmt.Sprintf(SEGNAME+"%d.ts", i)))
+		}
+	}
+
+	slateMu.Lock()
+	slate = video
+	slateMu.Unlock()
+	log.Println("[Slate] 슬레이트 준비 완료")
+
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	for _, channel := range channels {
+		channel.wakeRotation()
+	}
+}
+
+// buildSlate 슬레이트 영상을 HLS로 변환해서 slateDir 을 교체합니다.
+func buildSlate(slateDir string, stamp []byte) error {
+	if err := os.MkdirAll(tmpHlsDir, os.ModePerm); err != nil {
+		return err
+	}
+	tempDir, err := os.MkdirTemp(tmpHlsDir, SLATE_ID)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := slateConfig.Video
+	duration := slateConfig.Duration
+	if source == "" {
+		source = filepath.Join(tempDir, "card.mp4")
+		if err := generateSlateCard(source, slateConfig.Text, duration); err != nil {
+			return err
+		}
+	} else if duration, err = getVideoDuration(source); err != nil {
+		return err
+	}
+
+	hlsDir := filepath.Join(tempDir, "hls")
+	if err := convertToHLS(source, hlsDir, clipRange{}, "", duration, nil); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(hlsDir, slateStampFile), stamp, 0644); err != nil {
+		return err
+	}
+
+	// 영상 게시와 같이 다른 이름으로 복사한 뒤 교체
+	stagingDir := slateDir + ".tmp"
+	_ = os.RemoveAll(stagingDir)
+	if err := copyDir(hlsDir, stagingDir); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	if err := os.RemoveAll(slateDir); err != nil {
+		return err
+	}
+	return os.Rename(stagingDir, slateDir)
+}
+
+/*
+generateSlateCard 공통 해상도의 어두운 배경 가운데에 text 를 적은 무음 카드 영상을 만듭니다.
+
+글꼴을 찾지 못해서 글자를 넣을 수 없는 환경이면 배경만 있는 카드를 만듭니다.
+*/
+func generateSlateCard(outputPath string, text string, duration float64) error {
+	profile := normalizeConfig.Profile
+	background := fmt.Sprintf("color=c=0x1e1e2e:s=%dx%d:r=%s:d=%s", profile.Width, profile.Height,
+		strconv.FormatFloat(profile.FrameRate, 'f', -1, 64), strconv.FormatFloat(duration, 'f', 3, 64))
+	cardArgs := func(video string) []string {
+		args := []string{"-y", "-f", "lavfi", "-i", video,
+			"-f", "lavfi", "-i", "anullsrc=channel_layout=stereo:sample_rate=48000",
+			"-t", strconv.FormatFloat(duration, 'f', 3, 64),
+			"-c:v", "libx264", "-pix_fmt", "yuv420p", "-c:a", "aac", "-shortest"}
+		args = append(args, ffmpegThreadArgs()...)
+		return append(args, outputPath)
+	}
+
+	if text != "" {
+		// 필터 인자 escape 를 피하기 위해 글자는 파일로 넘김
+		textFile := outputPath + ".txt"
+		defer os.Remove(textFile)
+		if err := os.WriteFile(textFile, []byte(text), 0644); err != nil {
+			return err
+		}
+		drawText := fmt.Sprintf("drawtext=textfile='%s':expansion=none:fontcolor=white:fontsize=h/12:x=(w-text_w)/2:y=(h-text_h)/2",
+			escapeFilterPath(textFile))
+		err := runFfmpeg(cardArgs(background+","+drawText), duration, nil)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[Slate] 글자를 넣지 못해서 배경만 사용합니다: %v\n", err)
+	}
+	return runFfmpeg(cardArgs(background), duration, nil)
+}
+
+// escapeFilterPath 필터 인자의 따옴표 안에 넣을 파일 경로에서 특별한 의미를 가지는 문자를 escape 합니다.
+func escapeFilterPath(filePath string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `'\''`, `:`, `\:`).Replace(filePath)
+}
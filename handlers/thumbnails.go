@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 영상 디렉토리 내의 썸네일 파일 이름
+const (
+	POSTER_FILE     = "poster.jpg"
+	SPRITE_FILE     = "thumbnails.jpg"
+	SPRITE_VTT_FILE = "thumbnails.vtt"
+)
+
+// 썸네일 한 칸의 크기, 비율이 다른 영상은 검은 여백으로 채움
+const (
+	thumbnailWidth  = 160
+	thumbnailHeight = 90
+	spriteColumns   = 10
+)
+
+// 썸네일 간격 (초)
+var thumbnailInterval = loadThumbnailInterval()
+
+// loadThumbnailInterval THUMBNAIL_INTERVAL 환경 변수에서 썸네일 간격을 읽어옵니다.
+func loadThumbnailInterval() float64 {
+	return lookupFloat("THUMBNAIL_INTERVAL", 1, positive)
+}
+
+// thumbnailScale 썸네일 크기로 줄이고 남는 부분을 여백으로 채우는 필터
+func thumbnailScale(width, height int) string {
+	return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+		width, height, width, height)
+}
+
+/*
+generateThumbnails 함수는 영상의 clip 구간에서 대표 이미지(poster.jpg)와 미리보기용 썸네일 sprite(thumbnails.jpg, thumbnails.vtt)를 outputDir에 생성합니다.
+
+sprite는 thumbnailInterval 마다 한 장씩 spriteColumns 개씩 줄을 바꿔 이어 붙이고, WebVTT의 #xywh 로 시간대별 위치를 알려줍니다.
+*/
+func generateThumbnails(inputFilePath, outputDir string, clip clipRange, duration float64) error {
+	length := clip.Length(duration)
+	if length <= 0 {
+		return fmt.Errorf("invalid video length: %f", length)
+	}
+
+	// 첫 프레임은 검은 화면인 경우가 많아서 조금 뒤의 프레임을 사용
+	posterArgs := append([]string{"-y"}, ffmpegInputArgs(inputFilePath, clip)...)
+	posterArgs = append(posterArgs, "-ss", strconv.FormatFloat(min(1, length/2), 'f', 3, 64),
+		"-frames:v", "1", "-vf", thumbnailScale(1280, 720), "-q:v", "3")
+	posterArgs = append(posterArgs, ffmpegThreadArgs()...)
+	posterArgs = append(posterArgs, filepath.Join(outputDir, POSTER_FILE))
+	if err := runFfmpeg(posterArgs, 0, nil); err != nil {
+		return fmt.Errorf("failed to extract poster: %w", err)
+	}
+
+	count := int(math.Ceil(length / thumbnailInterval))
+	columns := min(count, spriteColumns)
+	rows := (count + columns - 1) / columns
+	spriteArgs := append([]string{"-y"}, ffmpegInputArgs(inputFilePath, clip)...)
+	spriteArgs = append(spriteArgs,
+		"-vf", fmt.Sprintf("fps=1/%s,%s,tile=%dx%d", strconv.FormatFloat(thumbnailInterval, 'f', -1, 64),
+			thumbnailScale(thumbnailWidth, thumbnailHeight), columns, rows),
+		"-frames:v", "1", "-q:v", "5")
+	spriteArgs = append(spriteArgs, ffmpegThreadArgs()...)
+	spriteArgs = append(spriteArgs, filepath.Join(outputDir, SPRITE_FILE))
+	if err := runFfmpeg(spriteArgs, length, nil); err != nil {
+		return fmt.Errorf("failed to generate thumbnail sprite: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(outputDir, SPRITE_VTT_FILE), spriteVTT(length, count, columns), 0644)
+}
+
+// spriteVTT 썸네일 sprite의 시간대별 위치를 담은 WebVTT
+func spriteVTT(length float64, count int, columns int) []byte {
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n")
+	for i := 0; i < count; i++ {
+		start := float64(i) * thumbnailInterval
+		end := min(start+thumbnailInterval, length)
+		fmt.Fprintf(&builder, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), SPRITE_FILE,
+			(i%columns)*thumbnailWidth, (i/columns)*thumbnailHeight, thumbnailWidth, thumbnailHeight)
+	}
+	return []byte(builder.String())
+}
+
+// vttTimestamp 초를 WebVTT 시간 형식으로 변환합니다. ex) 00:01:02.500
+func vttTimestamp(seconds float64) string {
+	milliseconds := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// CarouselItem 영상 목록 API 응답의 영상 하나, 썸네일이 없는 영상은 주소가 비어있음
+type CarouselItem struct {
+	Id           string  `json:"id"`
+	Uploader     string  `json:"uploader"`
+	OriginalName string  `json:"original_name"`
+	Duration     float64 `json:"duration"`
+	Poster       string  `json:"poster,omitempty"`
+	Thumbnails   string  `json:"thumbnails,omitempty"` // WebVTT 주소
+}
+
+// videoAssetURL 채널의 영상 디렉토리 내의 파일이 있으면 /hls 주소를, 없으면 빈 문자열을 리턴합니다.
+func (ch *Channel) videoAssetURL(id string, name string) string {
+	if _, err := os.Stat(filepath.Join(ch.hlsDir, id, name)); err != nil {
+		return ""
+	}
+	return ch.hlsURL() + "/" + id + "/" + name
+}
+
+// carouselItem Rider의 영상 정보와 썸네일 주소
+func (ch *Channel) carouselItem(segment *data_struct.Segment) CarouselItem {
+	return CarouselItem{
+		Id:           segment.ID(),
+		Uploader:     segment.Uploader,
+		OriginalName: segment.OriginalName,
+		Duration:     segment.Duration(),
+		Poster:       ch.videoAssetURL(segment.ID(), POSTER_FILE),
+		Thumbnails:   ch.videoAssetURL(segment.ID(), SPRITE_VTT_FILE),
+	}
+}
+
+/* CarouselHandler 채널 Merry-Go에 타고 있는 영상을 재생 순서대로 썸네일 주소와 함께 리턴합니다.
+ */
+func CarouselHandler(c *fiber.Ctx) error {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+
+	items := []CarouselItem{}
+	for segment := range ch.merryGo.Values() {
+		items = append(items, ch.carouselItem(segment))
+	}
+	return c.JSON(items)
+}
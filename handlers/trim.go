@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TrimConfig 업로드된 영상의 최대 길이와 넘을 때의 처리 방식
+type TrimConfig struct {
+	Auto      bool    // true 이면 최대 길이를 넘는 영상을 거부하지 않고 앞에서부터 최대 길이만큼 자름
+	MaxLength float64 // 최대 길이 (초)
+}
+
+var trimConfig = loadTrimConfig()
+
+// loadTrimConfig AUTO_TRIM, MAX_VIDEO_LENGTH 환경 변수에서 설정을 읽어옵니다.
+func loadTrimConfig() TrimConfig {
+	config := TrimConfig{MaxLength: MAX_VIDEO_LENGTH}
+
+	config.Auto = lookupBool("AUTO_TRIM", config.Auto)
+	config.MaxLength = lookupFloat("MAX_VIDEO_LENGTH", config.MaxLength, positive)
+	return config
+}
+
+/*
+clipRange 영상에서 잘라낼 구간, Duration이 0이면 자르지 않고 전체를 사용합니다.
+
+ffmpeg 입력 옵션(-ss, -t)으로 넘기므로 다시 인코딩할 때는 정확한 위치에서, 원본을 복사할 때는 가까운 keyframe에서 잘립니다.
+*/
+type clipRange struct {
+	Start    float64
+	Duration float64
+}
+
+// Length 잘라낸 뒤의 길이 (초)
+func (r clipRange) Length(total float64) float64 {
+	if r.Duration > 0 {
+		return r.Duration
+	}
+	return total
+}
+
+// inputArgs 입력 파일 앞에 붙는 -ss, -t 인자
+func (r clipRange) inputArgs() []string {
+	if r.Duration <= 0 {
+		return nil
+	}
+	return []string{
+		"-ss", strconv.FormatFloat(r.Start, 'f', 3, 64),
+		"-t", strconv.FormatFloat(r.Duration, 'f', 3, 64),
+	}
+}
+
+// parseSeconds start, end 값을 초 단위로 읽습니다. 빈 문자열은 0
+func parseSeconds(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	return seconds, nil
+}
+
+/*
+resolveClip 업로드한 사람이 고른 구간(start, end)과 최대 길이로 실제로 잘라낼 구간을 정합니다.
+
+end 가 0이면 영상 끝까지를 의미합니다. 고른 구간이 최대 길이를 넘으면 config.Auto 일 때 start 부터 최대 길이만큼 자르고, 아니면 에러를 리턴합니다.
+*/
+func resolveClip(duration float64, start float64, end float64, config TrimConfig) (clipRange, error) {
+	if start >= duration || (end > 0 && end <= start) {
+		return clipRange{}, errors.New("invalid start/end range")
+	}
+	if end <= 0 || end > duration {
+		end = duration
+	}
+
+	if end-start > config.MaxLength {
+		if !config.Auto {
+			return clipRange{}, fmt.Errorf("video duration exceeds %s seconds", strconv.FormatFloat(config.MaxLength, 'f', -1, 64))
+		}
+		end = start + config.MaxLength
+	}
+
+	if start == 0 && end == duration {
+		return clipRange{}, nil
+	}
+	return clipRange{Start: start, Duration: end - start}, nil
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	JOB_QUEUED      = "queued"
+	JOB_PROBING     = "probing"
+	JOB_TRANSCODING = "transcoding"
+	JOB_PUBLISHING  = "publishing"
+	JOB_DONE        = "done"
+	JOB_FAILED      = "failed"
+)
+
+const (
+	defaultUploadWorkers = 1
+	uploadQueueSize      = 32
+	jobRetention         = time.Hour // 끝난 작업 상태를 보관하는 시간
+)
+
+/*
+UploadJob 업로드된 영상을 HLS로 변환해서 Merry-Go에 태우는 작업 하나
+
+작업 ID는 영상 ID로도 사용합니다. 상태는 worker 고루틴과 상태 조회 API에서 함께 읽으므로 mu로 보호합니다.
+*/
+type UploadJob struct {
+	mu           sync.Mutex
+	Id           string
+	Status       string
+	Progress     float64 // 현재 단계의 진행률 0 ~ 1
+	Error        string
+	Uploader     string
+	OriginalName string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	channel   string  // 영상을 태울 채널 이름
+	filePath  string  // 저장된 업로드 파일 경로
+	trimStart float64 // 업로드한 사람이 고른 시작 위치 (초)
+	trimEnd   float64 // 업로드한 사람이 고른 끝 위치 (초), 0 이면 끝까지
+	loudness  float64 // 측정된 통합 음량 (LUFS), 측정하지 않았으면 0
+}
+
+// JobStatus 작업 상태 조회 API 응답
+type JobStatus struct {
+	Id           string    `json:"id"`
+	Status       string    `json:"status"`
+	Progress     float64   `json:"progress"`
+	Error        string    `json:"error,omitempty"`
+	OriginalName string    `json:"original_name"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 작업 목록, 끝난 작업도 jobRetention 동안 조회할 수 있도록 보관
+var jobs = make(map[string]*UploadJob)
+var jobsMu sync.Mutex
+
+// 대기 중인 작업 큐
+var jobQueue = make(chan *UploadJob, uploadQueueSize)
+
+// 작업이 끝나기 전에 영상을 태울 채널이 삭제됨
+var errChannelDeleted = errors.New("channel was deleted")
+
+// Snapshot 현재 작업 상태의 복사본
+func (j *UploadJob) Snapshot() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return JobStatus{
+		Id:           j.Id,
+		Status:       j.Status,
+		Progress:     j.Progress,
+		Error:        j.Error,
+		OriginalName: j.OriginalName,
+		CreatedAt:    j.CreatedAt,
+		UpdatedAt:    j.UpdatedAt,
+	}
+}
+
+// setStatus 다음 단계로 넘어가며 진행률을 초기화합니다.
+func (j *UploadJob) setStatus(status string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = status
+	j.Progress = 0
+	j.UpdatedAt = time.Now()
+}
+
+func (j *UploadJob) setProgress(progress float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Progress = progress
+	j.UpdatedAt = time.Now()
+}
+
+func (j *UploadJob) fail(err error) {
+	j.mu.Lock()
+	j.Status = JOB_FAILED
+	j.Error = err.Error()
+	j.UpdatedAt = time.Now()
+	j.mu.Unlock()
+
+	log.Printf("[Job] %s 실패: %v\n", j.Id, err)
+	publishEvent(Event{Type: EVENT_JOB_FAILED, Channel: j.channel, JobID: j.Id, Data: j.Snapshot()})
+}
+
+/*
+enqueueJob 작업을 목록에 등록하고 큐에 넣습니다. 큐가 가득 차 있으면 false
+*/
+func enqueueJob(job *UploadJob) bool {
+	jobsMu.Lock()
+	pruneJobs()
+	jobs[job.Id] = job
+	jobsMu.Unlock()
+
+	select {
+	case jobQueue <- job:
+		return true
+	default:
+		jobsMu.Lock()
+		delete(jobs, job.Id)
+		jobsMu.Unlock()
+		return false
+	}
+}
+
+// pruneJobs 끝난 지 jobRetention 이 지난 작업을 목록에서 지웁니다. 호출하는 쪽에서 jobsMu를 잡고 있어야 합니다.
+func pruneJobs() {
+	for id, job := range jobs {
+		status := job.Snapshot()
+		if (status.Status == JOB_DONE || status.Status == JOB_FAILED) && time.Since(status.UpdatedAt) > jobRetention {
+			delete(jobs, id)
+		}
+	}
+}
+
+// loadUploadWorkers UPLOAD_WORKERS 환경 변수에서 동시에 변환할 영상 수를 읽어옵니다.
+func loadUploadWorkers() int {
+	return lookupInt("UPLOAD_WORKERS", defaultUploadWorkers, positive)
+}
+
+/*
+StartUploadWorkers UPLOAD_WORKERS 개의 worker 고루틴을 실행하여 큐에 들어온 작업을 처리합니다.
+*/
+func StartUploadWorkers() {
+	workers := loadUploadWorkers()
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobQueue {
+				processJob(job)
+			}
+		}()
+	}
+	log.Printf("Upload worker %d개 실행\n", workers)
+}
+
+/*
+processJob 업로드된 영상을 검사(probing), 변환(transcoding), 게시(publishing) 순서로 처리합니다.
+*/
+func processJob(job *UploadJob) {
+	defer func(filePath string) {
+		err := deleteTempUploadedFile(filePath)
+		if err != nil {
+			log.Println("Failed to delete temp uploaded file: ", err)
+		}
+	}(job.filePath)
+
+	// 허용된 형식의 영상인지 확인
+	job.setStatus(JOB_PROBING)
+	if _, err := validateUpload(job.filePath, validationRules); err != nil {
+		job.fail(fmt.Errorf("invalid video: %w", err))
+		return
+	}
+
+	// 동영상 길이 확인
+	duration, err := getVideoDuration(job.filePath)
+	if err != nil {
+		job.fail(fmt.Errorf("failed to get video duration: %w", err))
+		return
+	}
+
+	// 고른 구간이나 동영상 길이가 채널의 최대 길이를 초과하면 설정에 따라 자르거나 업로드 거부
+	ch, ok := lookupChannel(job.channel)
+	if !ok {
+		job.fail(errChannelDeleted)
+		return
+	}
+	clip, err := resolveClip(duration, job.trimStart, job.trimEnd, ch.trimConfig())
+	if err != nil {
+		job.fail(err)
+		return
+	}
+
+	// tmpHlsDir 디렉토리가 존재하는지 확인하고, 없으면 생성
+	if err := os.MkdirAll(tmpHlsDir, os.ModePerm); err != nil {
+		job.fail(fmt.Errorf("failed to create directory: %w", err))
+		return
+	}
+
+	tempVideoDir := filepath.Join(tmpHlsDir, job.Id)
+	defer func(directory string) {
+		err := os.RemoveAll(directory)
+		if err != nil {
+			log.Println("Failed to delete TempSegments", err)
+		}
+	}(tempVideoDir)
+
+	job.setStatus(JOB_TRANSCODING)
+
+	// 공통 코덱 설정과 다른 영상만 다시 인코딩, 이 경우 진행률의 앞 절반은 코덱 변환, 뒤 절반은 HLS 변환
+	hlsInputPath := job.filePath
+	progressBase, progressSpan := 0.0, 1.0
+	if normalizeConfig.Enabled {
+		normalizedPath, converted, err := normalizeVideo(job.filePath, normalizeConfig.Profile, clip, duration, func(progress float64) {
+			job.setProgress(progress / 2)
+		})
+		if err != nil {
+			job.fail(err)
+			return
+		}
+		if converted {
+			defer func(filePath string) {
+				err := deleteTempUploadedFile(filePath)
+				if err != nil {
+					log.Println("Failed to delete normalized file: ", err)
+				}
+			}(normalizedPath)
+			hlsInputPath = normalizedPath
+			progressBase, progressSpan = 0.5, 0.5
+			// 이미 잘라낸 파일이므로 HLS 변환은 전체를 사용
+			clip, duration = clipRange{}, clip.Length(duration)
+		}
+	}
+
+	// 음량 측정이 있으면 남은 진행률의 앞 절반은 측정, 뒤 절반은 HLS 변환
+	audioFilter := ""
+	if loudnessConfig.Enabled {
+		progressSpan /= 2
+		measurement, err := measureLoudness(hlsInputPath, loudnessConfig, clip, duration, func(progress float64) {
+			job.setProgress(progressBase + progress*progressSpan)
+		})
+		if err != nil {
+			job.fail(err)
+			return
+		}
+		progressBase += progressSpan
+
+		if measurement != nil {
+			loudness, err := measurement.Integrated()
+			// 무음이거나 측정할 수 없는 오디오는 그대로 사용
+			if err == nil && loudness > silenceLoudness {
+				job.loudness = loudness
+				audioFilter = measurement.Filter(loudnessConfig)
+			}
+		}
+	}
+
+	err = convertToHLS(hlsInputPath, tempVideoDir, clip, audioFilter, duration, func(progress float64) {
+		job.setProgress(progressBase + progress*progressSpan)
+	})
+	if err != nil {
+		job.fail(fmt.Errorf("failed to convert video to HLS format: %w", err))
+		return
+	}
+
+	// 썸네일은 없어도 재생할 수 있으므로 실패해도 계속 진행
+	if err := generateThumbnails(hlsInputPath, tempVideoDir, clip, duration); err != nil {
+		log.Printf("[Job] %s 썸네일 생성 실패: %v\n", job.Id, err)
+	}
+
+	job.setStatus(JOB_PUBLISHING)
+	err = publishJob(job, tempVideoDir)
+	if err != nil {
+		job.fail(err)
+		return
+	}
+
+	job.setStatus(JOB_DONE)
+	job.setProgress(1)
+	log.Printf("[Job] %s 영상이 Merry-Go에 들어갔습니다.\n", job.Id)
+	publishEvent(Event{Type: EVENT_VIDEO_PUBLISHED, Channel: job.channel, JobID: job.Id, Data: job.Snapshot()})
+}
+
+// publishJob 변환이 끝난 영상을 채널 Merry-Go에 태웁니다. 꽉 찬 상태라면 정책에 따라 가장 오래된 영상을 빼고 자리를 만듭니다.
+func publishJob(job *UploadJob, tempVideoDir string) error {
+	ch, ok := lookupChannel(job.channel)
+	if !ok {
+		return errChannelDeleted
+	}
+
+	// Merry-Go와 플레이리스트 변경은 회전/삭제와 겹치지 않도록 mu 안에서 진행
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	// 변환하는 동안 채널이 삭제된 경우
+	if ch.deleted {
+		return errChannelDeleted
+	}
+	if ch.merryGo.IsFull() {
+		if !evictionPolicy.ReplaceOldest {
+			return errors.New("Merry-Go is Full")
+		}
+		err := ch.evictOldest()
+		if err != nil {
+			return fmt.Errorf("failed to evict oldest video: %w", err)
+		}
+	}
+
+	video := &data_struct.Segment{Id: job.Id, Uploader: job.Uploader, OriginalName: job.OriginalName, Loudness: job.loudness}
+	err := ch.publishVideo(tempVideoDir, video)
+	if err != nil {
+		return fmt.Errorf("failed to update HLS playlist: %w", err)
+	}
+	ch.persistMerryGo()
+	ch.wakeRotation()
+	return nil
+}
+
+/* JobStatusHandler 업로드 작업의 상태와 진행률을 조회합니다.
+ */
+func JobStatusHandler(c *fiber.Ctx) error {
+	jobsMu.Lock()
+	job, ok := jobs[c.Params("id")]
+	jobsMu.Unlock()
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("Job not found")
+	}
+
+	return c.JSON(job.Snapshot())
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"Merry-Go/data_struct"
+	"Merry-Go/m3u8"
 	"bytes"
 	"errors"
 	"fmt"
@@ -9,46 +10,42 @@ import (
 	"github.com/google/uuid"
 	"io"
 	"log"
-	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
-var muxUploadVideo sync.Mutex
-
 const (
-	hlsDir             = "static/hls"
-	tmpDir             = "upload_video_tmp"
-	SEGNAME            = "seg"
-	SPLITER            = "_"
-	PLAYLIST           = "playlist"
-	MAX_VIDEO_LENGTH   = 10
-	LENGTH_ADJUST      = 1.4
-	TAG_TARGETDURATION = "#EXT-X-TARGETDURATION"
-	TAG_MEDIALENGTH    = "#EXTINF"
+	hlsDir           = "static/hls"
+	tmpDir           = "upload_video_tmp"
+	SEGNAME          = "seg"
+	SPLITER          = "_"
+	PLAYLIST         = "playlist"
+	MAX_VIDEO_LENGTH = 10
+	VIDEO_PLAYLIST   = "index" // 영상 디렉토리 내의 개별 플레이리스트 이름
 )
 
 var absHlsDir, _ = filepath.Abs(hlsDir)
 var tmpHlsDir, _ = filepath.Abs(tmpDir)
-var tempLines = []string{
-	"#EXTM3U",
-	"#EXT-X-VERSION:3",
-	TAG_TARGETDURATION + ":13",
-	"#EXT-X-ALLOW-CACHE:NO", // 캐시 여부
-	"#EXT-X-MEDIA-SEQUENCE:0",
-}
-var mainPlaylistFile = filepath.Join(absHlsDir, PLAYLIST+".m3u8")
-var merryGo = data_struct.NewMerryGo(10)
 
-/* UploadHandler handles file uploads and converts them to HLS format
- */
+// 업로드된 파일과 이어 올리기 업로드 조각이 생성된 UUID 이름으로 저장되는 디렉토리
+var uploadDir = filepath.Join(tmpHlsDir, "uploads")
+
+/*
+UploadHandler handles file uploads and queues them for HLS conversion
+
+변환은 worker 고루틴에서 진행되므로 업로드 파일을 저장한 뒤 바로 작업 ID를 응답합니다. 진행 상황은 GET /jobs/:id 로 확인합니다.
+영상은 요청 경로의 채널 Merry-Go에 들어갑니다.
+*/
 func UploadHandler(c *fiber.Ctx) error {
-	if merryGo.IsFull() {
+	ch, err := requestChannel(c)
+	if err != nil {
+		return err
+	}
+	if ch.merryGo.IsFull() && !evictionPolicy.ReplaceOldest {
 		return c.Status(fiber.StatusBadRequest).SendString("Merry-Go is Full")
 	}
 
@@ -58,258 +55,74 @@ func UploadHandler(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).SendString("Failed to retrieve file from form-data")
 	}
 
-	// Save the uploaded file to the server
-	tempFilePath := filepath.Join(os.TempDir(), file.Filename)
-
-	if err := c.SaveFile(file, tempFilePath); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file")
+	// 사용할 구간 (초), 없으면 처음부터 끝까지
+	trimStart, err := parseSeconds(c.FormValue("start"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid start")
 	}
-	defer func(filePath string) {
-		err := deleteTempUploadedFile(filePath)
-		if err != nil {
-			log.Println("Failed to delete temp uploaded file: ", err)
-		}
-	}(tempFilePath)
-
-	// 동영상 길이 확인
-	duration, err := getVideoDuration(tempFilePath)
+	trimEnd, err := parseSeconds(c.FormValue("end"))
 	if err != nil {
-		log.Println("Failed to get video duration: ", err)
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to get video duration")
-	}
-
-	// 동영상 길이가 MAX_VIDEO_LENGTH를 초과하면 업로드 거부
-	if duration > MAX_VIDEO_LENGTH {
-		return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Video duration exceeds %d seconds", MAX_VIDEO_LENGTH))
-	}
-
-	//tempFile, err := os.Create(tempFilePath)
-	//if err != nil {
-	//	log.Println("Failed to create temporary file: ", err)
-	//	return c.Status(fiber.StatusInternalServerError).SendString("Failed to create temporary file")
-	//}
-	//defer func(tempFile *os.File) {
-	//	err := tempFile.Close()
-	//	if err != nil {
-	//		log.Println("Failed to close temporary file: ", err)
-	//	}
-	//}(tempFile)
-	//
-	//uploadedFile, err := file.Open()
-	//if err != nil {
-	//	log.Println("Failed to open uploaded file: ", err)
-	//	return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
-	//}
-	//defer func(uploadedFile multipart.File) {
-	//	err := uploadedFile.Close()
-	//	if err != nil {
-	//		log.Println("Failed to close uploaded file: ", err)
-	//	}
-	//}(uploadedFile)
-	//
-	//if _, err := io.Copy(tempFile, uploadedFile); err != nil {
-	//	log.Println("Failed to save file: ", err)
-	//	return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file")
-	//}
-
-	muxUploadVideo.Lock()
-	defer muxUploadVideo.Unlock()
-
-	// tmpHlsDir 디렉토리가 존재하는지 확인하고, 없으면 생성
-	if _, err := os.Stat(tmpHlsDir); os.IsNotExist(err) {
-		err = os.MkdirAll(tmpHlsDir, os.ModePerm)
-		if err != nil {
-			log.Fatal("failed to create directory: %w", err)
-		}
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid end")
 	}
 
-	// UUID 생성
+	// UUID 생성 - 작업 ID이자 영상 ID, 클라이언트가 보낸 파일 이름은 경로에 쓰지 않고 저장 파일 이름으로도 사용
 	fileKey := uuid.New().String()
-	tempSegmentName := fileKey + SPLITER + SEGNAME
-	tempPlaylistFilePath := filepath.Join(tmpHlsDir, tempSegmentName+".m3u8")
-	err = convertToHLS(tempFilePath, tempPlaylistFilePath)
-	if err != nil {
-		log.Println("Failed to convert video to HLS format: ", err)
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to convert video to HLS format")
-	}
 
-	defer func(directory string, pattern string) {
-		err := deleteTempSegments(directory, pattern)
-		if err != nil {
-			log.Println("Failed to delete TempSegments", err)
-		}
-	}(tmpHlsDir, fileKey)
+	// Save the uploaded file to the server
+	tempFilePath := filepath.Join(uploadDir, fileKey)
 
-	// Append new segments to the main playlist
-	//mainPlaylistFile := filepath.Join(absHlsDir, "playlist.m3u8")
-	err = appendToPlaylist(mainPlaylistFile, tempPlaylistFilePath, tempSegmentName)
-	if err != nil {
-		log.Println("Failed to update HLS playlist: ", err)
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to update HLS playlist")
+	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
+		log.Println("Failed to create upload directory: ", err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file")
 	}
-
-	return c.JSON(fiber.Map{"status": "success"})
-}
-
-// convertToHLS converts a video file to HLS format
-func convertToHLS(inputFilePath, outputFilePath string) error {
-	cmd := exec.Command("ffmpeg", "-i", inputFilePath, "-c:v", "copy", "-c:a", "copy",
-		"-start_number", "0", "-hls_time", "10", "-hls_list_size", "0", "-f", "hls", outputFilePath)
-
-	// Capture stderr output
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return err
+	if err := c.SaveFile(file, tempFilePath); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save file")
 	}
 
-	if err := cmd.Start(); err != nil {
-		return err
+	now := time.Now()
+	job := &UploadJob{
+		Id:           fileKey,
+		Status:       JOB_QUEUED,
+		Uploader:     c.FormValue("uploader"),
+		OriginalName: file.Filename,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		channel:      ch.Name,
+		filePath:     tempFilePath,
+		trimStart:    trimStart,
+		trimEnd:      trimEnd,
 	}
-
-	// Read stderr output
-	slurp, _ := io.ReadAll(stderr)
-	if err := cmd.Wait(); err != nil {
-		log.Println("ffmpeg command failed: ", err)
-		log.Println("ffmpeg stderr: ", string(slurp))
-		return err
+	if !enqueueJob(job) {
+		_ = deleteTempUploadedFile(tempFilePath)
+		return c.Status(fiber.StatusServiceUnavailable).SendString("Upload queue is full")
 	}
 
-	return nil
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"status": JOB_QUEUED, "id": fileKey, "job": "/jobs/" + fileKey})
 }
 
-// appendToPlaylist appends segments from the new file to the existing playlist
-func appendToPlaylist(mainPlaylistFile, tempPlaylistFile string, tempSegmentName string) error {
-	segCount, err := getLastSegmentNum()
-	if err != nil {
-		return err
-	}
-	if segCount == 0 {
-		mainPlaylist, err := os.ReadFile(mainPlaylistFile)
+// convertToHLS converts a video file to HLS format
+// outputDir/<화질 이름> 마다 index.m3u8 과 seg0.ts, seg1.ts ... 를 생성하고, 진행률을 onProgress 로 알립니다.
+// clip 구간이 있다면 해당 구간만 변환하고, audioFilter 가 있다면 오디오에 적용합니다.
+func convertToHLS(inputFilePath, outputDir string, clip clipRange, audioFilter string, duration float64, onProgress func(float64)) error {
+	for _, rendition := range renditions {
+		err := os.MkdirAll(filepath.Join(outputDir, rendition.Name), os.ModePerm)
 		if err != nil {
-			// mainPlaylist가 존재하지 않을 경우 그대로 진행
-			if os.IsNotExist(err) {
-				log.Println("mainPlaylist does not exist but ok: ", err)
-			} else {
-				log.Fatalf("Error reading file: %v", err)
-				return err
-			}
-		} else {
-			rawMainLines := strings.Split(string(mainPlaylist), "\n")
-			// Remove the #EXT-X-ENDLIST tag
-			// #EXT-X-ENDLIST tag 는 플레이 리스트가 끝나는 지점을 의미
-
-			for _, line := range rawMainLines {
-				if strings.HasPrefix(line, TAG_MEDIALENGTH+":") {
-					segCount++
-				}
-			}
-		}
-	}
-
-	// temp segment를 복사
-	filteredLines, segmentData, err := copySegments(tempPlaylistFile, tempSegmentName, absHlsDir)
-	if err != nil {
-		return err
-	}
-
-	// MerryGo에 Segment 데이터 삽입
-	err = merryGo.Append(segmentData)
-	if err != nil {
-		return err
-	}
-
-	// Read the main playlist content
-	mainPlaylist, err := os.ReadFile(mainPlaylistFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// mainPlaylist가 존재하지 않을 경우 새로 생성
-			log.Printf("mainPlaylist does not exist -- creating: %s\n", mainPlaylistFile)
-			combinedLines := append(tempLines, filteredLines...)
-			updateDurationTag(combinedLines)
-			err = os.WriteFile(mainPlaylistFile, []byte(strings.Join(combinedLines, "\n")), 0644)
-			if err != nil {
-				return err
-			}
-			return nil
-		} else {
-			log.Fatalf("Error reading file: %v", err)
 			return err
 		}
 	}
 
-	// 기존 플레이 리스트 -> mainLines 문자열 배열으로 변환
-	rawMainLines := strings.Split(string(mainPlaylist), "\n")
-	// Remove the #EXT-X-ENDLIST tag - for Live Streaming
-	// #EXT-X-ENDLIST tag 는 플레이 리스트가 끝나는 지점을 의미
-	var mainLines []string
-	for _, line := range rawMainLines {
-		trimmedLine := strings.TrimSpace(line)
-		if trimmedLine != "" && trimmedLine != "#EXT-X-ENDLIST" {
-			mainLines = append(mainLines, line)
-		}
-	}
-
-	// #EXT-X-DISCONTINUITY 태그 - 세그먼트 간 구분자 삽입
-	combinedLines := append(mainLines, "#EXT-X-DISCONTINUITY")
-	// Combine the main playlist and new segment lines
-	combinedLines = append(combinedLines, filteredLines...)
-	updateDurationTag(combinedLines)
-
-	// Write the combined lines back to the main playlist
-	err = os.WriteFile(mainPlaylistFile, []byte(strings.Join(combinedLines, "\n")), 0644)
+	hasAudio, err := hasAudioStream(inputFilePath)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-/*
-getLastSegmentNum 현재 segment 번호를 카운트해서 마지막 segment 파일의 숫자보다 1만큼 큰 숫자를 반환
-
-예시: seg1.ts seg2.ts 가 있으면 3을 반환
-*/
-func getLastSegmentNum() (int, error) {
-	// 폴더 내 파일 목록 읽기
-	files, err := os.ReadDir(absHlsDir)
-	if err != nil {
-		log.Fatal(err)
-		return 0, nil
-	}
-
-	// 정규 표현식 컴파일
-	re := regexp.MustCompile(SEGNAME + `(\d+)\.ts`)
-
-	maxNumber := 0
-
-	// 파일 목록 순회
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		// 파일 이름에서 숫자 추출
-		matches := re.FindStringSubmatch(file.Name())
-		if len(matches) > 1 {
-			number, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return maxNumber, err
-			}
-			if number > maxNumber {
-				maxNumber = number
-			}
-		}
-	}
-
-	// 마지막 숫자보다 1 큰 값
-	nextNumber := maxNumber + 1
-	return nextNumber, nil
+	return runFfmpeg(hlsArgs(inputFilePath, outputDir, hasAudio, clip, audioFilter), clip.Length(duration), onProgress)
 }
 
 // deleteTempSegments 함수는 업로드 시에 넣었던 임시 폴더 내의 업로드 파일을 삭제합니다.
 func deleteTempUploadedFile(filePath string) error {
 	log.Printf("Deleting file: %s\n", filePath)
-	err = os.Remove(filePath)
+	err := os.Remove(filePath)
 	if err != nil {
 		log.Printf("Deleting file Error: %s\n", err)
 		return err
@@ -317,71 +130,6 @@ func deleteTempUploadedFile(filePath string) error {
 	return nil
 }
 
-// deleteTempSegments 함수는 주어진 디렉토리 내에서 pattern 에 해당하는 문자열으로 시작하는 파일을 모두 삭제합니다.
-func deleteTempSegments(directory string, pattern string) error {
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// 파일 이름이 "temp_segment"로 시작하는지 확인
-		if !info.IsDir() && strings.HasPrefix(filepath.Base(path), pattern) {
-			log.Printf("Deleting file: %s\n", path)
-			err = os.Remove(path)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	return err
-}
-
-// updateDurationTag 인자로 받은 플레이 리스트 문자열 배열 내의 #EXT-X-TARGETDURATION 태그를 업데이트 합니다.
-func updateDurationTag(combinedLines []string) {
-	maxDuration := findMaxDuration(combinedLines)
-	newTargetDuration := fmt.Sprintf("%s:%d", TAG_TARGETDURATION, int(math.Ceil(maxDuration)))
-	for i, line := range combinedLines {
-		if strings.HasPrefix(line, TAG_TARGETDURATION+":") {
-			combinedLines[i] = newTargetDuration
-			break
-		}
-	}
-}
-
-// updateEndListTag 인자로 받은 플레이 리스트 문자열 배열 내에 #EXT-X-ENDLIST 태그를 업데이트 합니다.
-func updateEndListTag(combinedLines []string) []string {
-	return append(combinedLines, "#EXT-X-ENDLIST")
-}
-
-// updateSequenceTag 인자로 받은 플레이 리스트 문자열 배열 내의 #EXT-X-MEDIA-SEQUENCE 태그를 업데이트 합니다.
-func updateSequenceTag(combinedLines []string, start int) {
-	newSequence := fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d", start)
-	for i, line := range combinedLines {
-		if strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:") {
-			combinedLines[i] = newSequence
-			break
-		}
-	}
-}
-
-// findMaxDuration 플레이 리스트 내의 segment들 중에서 가장 긴 길이를 찾습니다
-func findMaxDuration(combinedLines []string) float64 {
-	// Find the maximum segment duration
-	maxDuration := 0.0
-	for _, line := range combinedLines {
-		if strings.HasPrefix(line, TAG_MEDIALENGTH+":") {
-			var duration float64
-			_, _ = fmt.Sscanf(line, TAG_MEDIALENGTH+":%f,", &duration)
-			if duration > maxDuration {
-				maxDuration = duration
-			}
-		}
-	}
-
-	return maxDuration
-}
-
 // copyFile 함수는 src 파일을 dst 파일로 복사합니다.
 func copyFile(src, dst string) error {
 	// 원본 파일 열기
@@ -419,159 +167,213 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-// copySegments 함수는 .m3u8 파일과 같은 폴더에 있는 세그먼트 파일을 특정 폴더로 복사합니다.
-// 복사할 때 segment들은 seg%d.ts 의 형태로 segCount에 따라서 다르게 복사됩니다.
-func copySegments(tempPlaylistPath, tempSegmentName string, destDir string) ([]string, *data_struct.Segment, error) {
-	// 업로드 플레이 리스트 읽기
-	var segmentLines []string
-	var segmentData = &data_struct.Segment{Start: 0, End: 0, Length: 0}
-	segCount, err := getLastSegmentNum()
+// copyDir 함수는 src 디렉토리를 하위 디렉토리까지 dst 로 복사합니다.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(sourcePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, sourcePath)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(dst, relPath)
+
+		if entry.IsDir() {
+			return os.MkdirAll(destPath, os.ModePerm)
+		}
+		err = copyFile(sourcePath, destPath)
+		if err != nil {
+			return fmt.Errorf("failed to copy file %s to %s: %w", sourcePath, destPath, err)
+		}
+		return nil
+	})
+}
+
+// publishVideo 함수는 변환이 끝난 영상 디렉토리를 채널의 hls 디렉토리로 옮기고 채널 Merry-Go에 태웁니다.
+// 호출하는 쪽에서 mu를 잡고 있어야 합니다.
+func (ch *Channel) publishVideo(tempVideoDir string, video *data_struct.Segment) error {
+	durations, err := readRenditionDurations(tempVideoDir)
 	if err != nil {
-		return segmentLines, segmentData, err
+		return err
 	}
 
-	//segmentData.Name = tempSegmentName
-	// TODO -- hls 플레이리스트에서 파일 간의 분할을 위한 태그가 있음. 이에 따라서 segment 이름과 숫자를 지정하지 않고 uuid 값 그대로 사용 가능, 이게 더 로직상 간단하고 직관적이라 변경하는게 좋을듯
+	// 다른 이름으로 먼저 복사한 뒤 rename 하여, 재생 중인 플레이어가 덜 복사된 파일을 보지 않도록 함
+	videoDir := filepath.Join(ch.hlsDir, video.ID())
+	stagingDir := videoDir + ".tmp"
+	err = os.MkdirAll(stagingDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	err = copyDir(tempVideoDir, stagingDir)
+	if err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	err = os.Rename(stagingDir, videoDir)
+	if err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	log.Printf("Published %s to %s\n", tempVideoDir, videoDir)
 
-	tempPlaylist, err := os.ReadFile(tempPlaylistPath)
+	// MerryGo에 Segment 데이터 삽입
+	video.Start = 0
+	video.End = len(durations) - 1
+	video.Durations = durations
+	video.Renditions = renditionNames()
+	video.Uploaded = time.Now()
+	err = ch.merryGo.Append(video)
 	if err != nil {
-		return segmentLines, segmentData, err
-	}
-
-	// 업로드 플레이 리스트 -> 문자열 배열으로 변환
-	segmentLines = strings.Split(string(tempPlaylist), "\n")
-	filteredLines := []string{}
-	tmpCount := segCount
-	segLength := 0.0
-	for _, line := range segmentLines {
-		// #EXTINF
-		if strings.HasPrefix(line, TAG_MEDIALENGTH+":") {
-			filteredLines = append(filteredLines, line)
-			parts := strings.Split(line, ":")
-			number, err := strconv.ParseFloat(parts[1][:len(parts[1])-1], 64)
-			if err != nil {
-				fmt.Println("Error:", err)
-				return segmentLines, segmentData, err
-			}
-			segLength += number
-		} else if strings.HasPrefix(line, tempSegmentName) {
-			parts := strings.Split(line, SPLITER)
-			if len(parts) < 2 {
-				return segmentLines, segmentData, errors.New("invalid segment name")
-			}
-			newSegLine := fmt.Sprintf(SEGNAME+"%d.ts", tmpCount)
-			// 세그먼트 부분만 사용
-			filteredLines = append(filteredLines, newSegLine)
-			tmpCount++
-		}
+		_ = os.RemoveAll(videoDir)
+		return err
 	}
-	segmentData.Length = int(math.Ceil(segLength * LENGTH_ADJUST))
 
-	// .m3u8 파일의 디렉토리 추출
-	sourceDir := filepath.Dir(tempPlaylistPath)
+	// 영상이 두 개가 되면 슬레이트를 끼워 넣지 않으므로 윈도우에 대기 중인 슬레이트를 빼고 다시 채움
+	if ch.merryGo.Len() == 2 && ch.live.Contains(SLATE_ID) {
+		ch.requeueWindow()
+		if err := ch.rebuildPlaylist(); err != nil {
+			log.Println("Failed to write playlist: ", err)
+		}
+	}
 
-	// .m3u8 파일 이름 추출 (확장자 제거)
-	baseName := strings.TrimSuffix(filepath.Base(tempPlaylistPath), filepath.Ext(tempPlaylistPath))
+	return nil
+}
 
-	// 대상 디렉토리가 존재하는지 확인하고, 없으면 생성
-	if _, err := os.Stat(destDir); os.IsNotExist(err) {
-		err = os.MkdirAll(destDir, os.ModePerm)
+/*
+readRenditionDurations 함수는 화질별 플레이리스트의 segment 길이를 읽고, 모든 화질의 segment 수가 같은지 확인합니다.
+함께 회전하기 위해 첫번째 화질의 길이를 기준으로 사용합니다.
+*/
+func readRenditionDurations(videoDir string) ([]float64, error) {
+	var reference []float64
+	for i, rendition := range renditions {
+		durations, err := readSegmentDurations(filepath.Join(videoDir, rendition.Name, VIDEO_PLAYLIST+".m3u8"))
 		if err != nil {
-			return filteredLines, segmentData, fmt.Errorf("failed to create destination directory: %w", err)
+			return nil, err
+		}
+		if len(durations) == 0 {
+			return nil, fmt.Errorf("rendition %s has no segments", rendition.Name)
+		}
+		if i == 0 {
+			reference = durations
+			continue
+		}
+		if len(durations) != len(reference) {
+			return nil, fmt.Errorf("rendition %s has %d segments, expected %d", rendition.Name, len(durations), len(reference))
 		}
 	}
+	if len(reference) == 0 {
+		return nil, errors.New("converted video has no segments")
+	}
+	return reference, nil
+}
 
-	// 소스 디렉토리 내의 모든 파일 읽기
-	files, err := os.ReadDir(sourceDir)
+// readSegmentDurations 함수는 플레이리스트 파일에서 각 segment의 #EXTINF 길이를 순서대로 읽어옵니다.
+func readSegmentDurations(playlistPath string) ([]float64, error) {
+	mediaPlaylist, err := m3u8.ReadFile(playlistPath)
 	if err != nil {
-		return filteredLines, segmentData, fmt.Errorf("failed to read source directory: %w", err)
-	}
-
-	// 세그먼트 파일 복사
-	segmentData.Start = segCount
-	for _, file := range files {
-		if strings.HasPrefix(file.Name(), baseName) && strings.HasSuffix(file.Name(), ".ts") {
-			newFileName := fmt.Sprintf(SEGNAME+"%d.ts", segCount)
-			segCount++
-			sourceFilePath := filepath.Join(sourceDir, file.Name())
-			destFilePath := filepath.Join(destDir, newFileName)
-			err := copyFile(sourceFilePath, destFilePath)
-			if err != nil {
-				return filteredLines, segmentData, fmt.Errorf("failed to copy file %s to %s: %w", sourceFilePath, destFilePath, err)
-			}
-			log.Printf("Copied %s to %s\n", sourceFilePath, destFilePath)
-		}
+		return nil, err
 	}
-	segmentData.End = segCount - 1
-
-	return filteredLines, segmentData, nil
+	return mediaPlaylist.Durations(), nil
 }
 
-func LoadHls() error {
-	// Read the main playlist content
-	mainPlaylist, err := os.ReadFile(mainPlaylistFile)
+/*
+load 서버 시작 시 채널의 Merry-Go를 복구합니다. DB에 저장된 영상이 있으면 DB를 기준으로 복구하고,
+기본 채널은 저장된 영상이 없으면 이전 버전의 playlist.m3u8을 읽어서 복구한 뒤 DB에 저장합니다.
+*/
+func (ch *Channel) load() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if err := os.MkdirAll(ch.hlsDir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := ch.loadSchedule(); err != nil {
+		return err
+	}
+	loaded, err := ch.loadFromDatabase()
 	if err != nil {
+		return err
+	}
+	ch.loadPlaylistState()
+	if loaded {
+		log.Printf("DB에서 %q 채널의 Merry-Go를 복구했습니다. 영상 %d개\n", ch.Name, ch.merryGo.Len())
+		return nil
+	}
+	if ch.Name != DEFAULT_CHANNEL {
+		return nil
+	}
+
+	// Read the main playlist content
+	mainPlaylistFile := ch.playlistFile()
+	if _, err := os.Stat(mainPlaylistFile); os.IsNotExist(err) {
 		log.Println("MainPlayList가 존재하지 않습니다. 파일이 없다고 가정하고 서버를 부팅합니다.")
 		return nil
 	}
+	mainPlaylist, err := m3u8.ReadFile(mainPlaylistFile)
+	if err != nil {
+		return err
+	}
 	log.Println("MainPlayList가 존재합니다. 기존 파일들을 Merry-Go에 입력합니다.")
-	rawMainLines := strings.Split(string(mainPlaylist), "\n")
 
-	// 정규 표현식 컴파일
-	re := regexp.MustCompile(SEGNAME + `(\d+)\.ts`)
+	// 이전 버전의 플레이리스트는 hls 디렉토리 바로 아래의 seg1.ts, seg2.ts ... 를 사용
+	re := regexp.MustCompile(`^` + SEGNAME + `(\d+)\.ts$`)
+
+	startIndex := -1
+	endIndex := -1
+	var durations []float64
 
-	startIndex := 0
-	endIndex := 0
-	segLength := 0.0
+	appendLegacy := func() error {
+		if startIndex < 0 {
+			return nil
+		}
+		segment := &data_struct.Segment{Id: uuid.New().String(), Start: startIndex, End: endIndex, Uploaded: time.Now(), Durations: durations}
+		startIndex = -1
+		endIndex = -1
+		durations = nil
 
-	// 파일 목록 순회
-	for _, line := range rawMainLines {
-		// #EXTINF
-		if strings.HasPrefix(line, TAG_MEDIALENGTH+":") {
-			parts := strings.Split(line, ":")
-			number, err := strconv.ParseFloat(parts[1][:len(parts[1])-1], 64)
+		// 영상 디렉토리 구조로 옮긴 뒤 Merry-Go에 입력
+		err := ch.migrateLegacySegments(segment)
+		if err != nil {
+			return err
+		}
+		err = ch.merryGo.Append(segment)
+		log.Printf("Merry-Go %d 번째 데이터 : %s, %f", ch.merryGo.Len(), segment.ID(), segment.Duration())
+		return err
+	}
+
+	// segment 목록 순회, 구분자 태그를 기준으로 영상을 나눔
+	for _, mediaSegment := range mainPlaylist.Segments {
+		if mediaSegment.Discontinuity {
+			err = appendLegacy()
 			if err != nil {
-				fmt.Println("Error:", err)
 				return err
 			}
-			segLength += number
-		} else {
-			// 파일 이름에서 숫자 추출
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				number, err := strconv.Atoi(matches[1])
-				if err != nil {
-					return err
-				}
-				if startIndex == 0 {
-					startIndex = number
-					endIndex = number
-				} else {
-					endIndex = number
-				}
-			} else if line == "#EXT-X-DISCONTINUITY" {
-				err = merryGo.Append(&data_struct.Segment{Start: startIndex, End: endIndex, Length: int(math.Ceil(segLength * LENGTH_ADJUST))})
-				log.Printf("Merry-Go %d 번째 데이터 : %d, %d, %f", merryGo.Count, startIndex, endIndex, segLength)
-				startIndex = 0
-				endIndex = 0
-				segLength = 0.0
-				if err != nil {
-					return err
-				}
-			}
 		}
-	}
 
-	if startIndex != 0 && endIndex != 0 && segLength != 0.0 {
-		err = merryGo.Append(&data_struct.Segment{Start: startIndex, End: endIndex, Length: int(math.Ceil(segLength * LENGTH_ADJUST))})
-		log.Printf("Merry-Go %d 번째 데이터 : %d, %d, %f", merryGo.Count, startIndex, endIndex, segLength)
-		startIndex = 0
-		endIndex = 0
-		segLength = 0.0
+		// 파일 이름에서 숫자 추출
+		matches := re.FindStringSubmatch(mediaSegment.URI)
+		if len(matches) < 2 {
+			continue
+		}
+		number, err := strconv.Atoi(matches[1])
 		if err != nil {
 			return err
 		}
+		if startIndex < 0 {
+			startIndex = number
+		}
+		endIndex = number
+		durations = append(durations, mediaSegment.Duration)
+	}
+	err = appendLegacy()
+	if err != nil {
+		return err
 	}
+	ch.removeOrphanVideos()
+
+	// 다음 시작부터는 DB를 기준으로 복구
+	ch.persistMerryGo()
 
 	return nil
 }
@@ -582,8 +384,9 @@ func LoadHls() error {
 return: 비디오 길이 float64, error
 */
 func getVideoDuration(filePath string) (float64, error) {
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries",
+	cmd, cancel := probeCommand("-v", "error", "-show_entries",
 		"format=duration", "-of", "default=noprint_wrappers=1:nokey=1", filePath)
+	defer cancel()
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -600,3 +403,23 @@ func getVideoDuration(filePath string) (float64, error) {
 	}
 	return duration, nil
 }
+
+/*
+	hasAudioStream 함수는 FFprobe를 사용하여 동영상에 오디오 스트림이 있는지 확인합니다.
+
+return: 오디오 스트림 존재 여부 bool, error
+*/
+func hasAudioStream(filePath string) (bool, error) {
+	cmd, cancel := probeCommand("-v", "error", "-select_streams", "a",
+		"-show_entries", "stream=index", "-of", "csv=p=0", filePath)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("error executing ffprobe command: %v, stderr: %s", err, stderr.String())
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationRules 업로드된 영상에 허용하는 컨테이너, 코덱, 해상도, 프레임 레이트
+type ValidationRules struct {
+	Containers   []string // 파일 앞부분의 magic byte로 판별한 컨테이너
+	VideoCodecs  []string // ffprobe codec_name
+	AudioCodecs  []string // ffprobe codec_name
+	MaxWidth     int
+	MaxHeight    int
+	MaxFrameRate float64
+}
+
+var validationRules = loadValidationRules()
+
+/*
+loadValidationRules UPLOAD_CONTAINERS, UPLOAD_VIDEO_CODECS, UPLOAD_AUDIO_CODECS, UPLOAD_MAX_RESOLUTION, UPLOAD_MAX_FPS 환경 변수에서
+허용 목록을 읽어옵니다. 목록은 쉼표로 구분합니다.
+*/
+func loadValidationRules() ValidationRules {
+	rules := ValidationRules{
+		Containers:   lookupList("UPLOAD_CONTAINERS", "mp4,mov,webm,mkv"),
+		VideoCodecs:  lookupList("UPLOAD_VIDEO_CODECS", "h264,hevc,vp8,vp9,av1"),
+		AudioCodecs:  lookupList("UPLOAD_AUDIO_CODECS", "aac,mp3,opus,vorbis"),
+		MaxWidth:     3840,
+		MaxHeight:    2160,
+		MaxFrameRate: 60,
+	}
+
+	if value, exists := os.LookupEnv("UPLOAD_MAX_RESOLUTION"); exists {
+		width, height, err := parseResolution(value)
+		if err != nil {
+			log.Printf("Error parsing UPLOAD_MAX_RESOLUTION: %s\n", value)
+		} else {
+			rules.MaxWidth = width
+			rules.MaxHeight = height
+		}
+	}
+	rules.MaxFrameRate = lookupFloat("UPLOAD_MAX_FPS", rules.MaxFrameRate, positive)
+
+	return rules
+}
+
+// parseResolution <가로>x<세로> 형식의 해상도를 읽습니다. 두 값 모두 양의 짝수여야 합니다.
+func parseResolution(value string) (int, int, error) {
+	widthStr, heightStr, _ := strings.Cut(value, "x")
+	width, widthErr := strconv.Atoi(widthStr)
+	height, heightErr := strconv.Atoi(heightStr)
+	if widthErr != nil || heightErr != nil || width <= 0 || height <= 0 || width%2 != 0 || height%2 != 0 {
+		return 0, 0, fmt.Errorf("invalid resolution: %s", value)
+	}
+	return width, height, nil
+}
+
+/*
+sniffContainer 파일 앞부분의 magic byte로 컨테이너 형식을 판별합니다. 클라이언트가 보낸 파일 이름이나 Content-Type은 믿지 않습니다.
+
+return: mp4, mov, webm, mkv, avi, ts, flv 중 하나, 알 수 없으면 빈 문자열
+*/
+func sniffContainer(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	// ISO base media - [size]ftyp[brand]
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		if bytes.Equal(header[8:12], []byte("qt  ")) {
+			return "mov", nil
+		}
+		return "mp4", nil
+	// EBML - DocType으로 webm과 mkv 구분
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		if bytes.Contains(header, []byte("webm")) {
+			return "webm", nil
+		}
+		return "mkv", nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("AVI ")):
+		return "avi", nil
+	case bytes.HasPrefix(header, []byte("FLV")):
+		return "flv", nil
+	// MPEG-TS - 188 byte 패킷마다 sync byte 0x47
+	case len(header) > 376 && header[0] == 0x47 && header[188] == 0x47 && header[376] == 0x47:
+		return "ts", nil
+	}
+	return "", nil
+}
+
+/*
+validateUpload 업로드된 파일이 허용 목록에 맞는 영상인지 확인합니다.
+
+컨테이너는 magic byte로, 코덱과 해상도, 프레임 레이트는 자원 제한을 건 ffprobe로 확인하며, 비디오 스트림이 없는 파일은 거부합니다.
+
+return: ffprobe 결과 *videoProbe, error
+*/
+func validateUpload(filePath string, rules ValidationRules) (*videoProbe, error) {
+	container, err := sniffContainer(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if container == "" {
+		return nil, errors.New("unknown file format")
+	}
+	if !slices.Contains(rules.Containers, container) {
+		return nil, fmt.Errorf("container %s is not allowed", container)
+	}
+
+	probe, err := probeVideo(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if probe.Video == nil {
+		return nil, errors.New("no video stream")
+	}
+	if !slices.Contains(rules.VideoCodecs, probe.Video.CodecName) {
+		return nil, fmt.Errorf("video codec %s is not allowed", probe.Video.CodecName)
+	}
+	if probe.Audio != nil && !slices.Contains(rules.AudioCodecs, probe.Audio.CodecName) {
+		return nil, fmt.Errorf("audio codec %s is not allowed", probe.Audio.CodecName)
+	}
+
+	// 세로 영상도 허용하도록 긴 쪽과 짧은 쪽을 각각 비교
+	long, short := max(probe.Video.Width, probe.Video.Height), min(probe.Video.Width, probe.Video.Height)
+	if short <= 0 || long > max(rules.MaxWidth, rules.MaxHeight) || short > min(rules.MaxWidth, rules.MaxHeight) {
+		return nil, fmt.Errorf("resolution %dx%d is not allowed", probe.Video.Width, probe.Video.Height)
+	}
+	frameRate := probe.Video.FrameRate()
+	if frameRate <= 0 || frameRate > rules.MaxFrameRate {
+		return nil, fmt.Errorf("frame rate %s is not allowed", probe.Video.AvgFrameRate)
+	}
+
+	return probe, nil
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"Merry-Go/data_struct"
+	"Merry-Go/database"
+	"Merry-Go/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// videoFromSegment Merry-Go의 Segment를 DB에 저장할 Video 모델로 변환합니다.
+func videoFromSegment(segment *data_struct.Segment, channel string, position int) models.Video {
+	start, end := segment.Info()
+	durations, _ := json.Marshal(segment.Durations)
+	renditionNames, _ := json.Marshal(segment.Renditions)
+
+	return models.Video{
+		Id:           segment.ID(),
+		Channel:      channel,
+		SegStart:     start,
+		SegEnd:       end,
+		Durations:    string(durations),
+		Duration:     segment.Duration(),
+		Renditions:   string(renditionNames),
+		Uploader:     segment.Uploader,
+		OriginalName: segment.OriginalName,
+		Loudness:     segment.Loudness,
+		PlayCount:    segment.PlayCount(),
+		Likes:        segment.LikeCount(),
+		Position:     position,
+		UploadedAt:   segment.UploadedAt(),
+	}
+}
+
+// segmentFromVideo DB의 Video 모델을 Merry-Go에 태울 Segment로 변환합니다.
+func segmentFromVideo(video models.Video) (*data_struct.Segment, error) {
+	var durations []float64
+	if err := json.Unmarshal([]byte(video.Durations), &durations); err != nil {
+		return nil, fmt.Errorf("invalid durations of video %s: %w", video.Id, err)
+	}
+	var renditionNames []string
+	if video.Renditions != "" {
+		if err := json.Unmarshal([]byte(video.Renditions), &renditionNames); err != nil {
+			return nil, fmt.Errorf("invalid renditions of video %s: %w", video.Id, err)
+		}
+	}
+
+	return &data_struct.Segment{
+		Id:           video.Id,
+		Start:        video.SegStart,
+		End:          video.SegEnd,
+		Plays:        video.PlayCount,
+		Likes:        video.Likes,
+		Uploaded:     video.UploadedAt,
+		Durations:    durations,
+		Renditions:   renditionNames,
+		Uploader:     video.Uploader,
+		OriginalName: video.OriginalName,
+		Loudness:     video.Loudness,
+	}, nil
+}
+
+/*
+persistMerryGo 채널 Merry-Go의 순서를 DB에 저장하고, 바뀐 상태를 /wse 로 알립니다.
+
+영상의 Position은 Head와 관계없는 순환 순서이므로, Merry-Go가 회전하거나 영상이 빠지기만 했다면 영상은 다시 쓰지 않습니다.
+새로 탄 영상이 있거나 순서가 바뀌었다면 Head부터 Position을 다시 매기고, 값이 바뀐 영상만 저장합니다.
+재생 횟수는 persistPlayed 로 재생이 끝난 영상만 저장합니다.
+
+호출하는 쪽에서 mu를 잡고 있어야 합니다.
+*/
+func (ch *Channel) persistMerryGo() {
+	// 순서를 다시 쓰지 않는 경우에도 재생 중인 영상이나 재생 횟수는 바뀌었을 수 있음
+	defer ch.notifyMerryGoChanged()
+
+	riders, err := ch.merryGo.Display()
+	if err != nil || ch.onlyRotated(riders) {
+		return
+	}
+
+	positions := make(map[string]int, len(riders))
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for position, segment := range riders {
+			positions[segment.ID()] = position
+			saved, ok := ch.savedPositions[segment.ID()]
+			if ok && saved == position {
+				continue
+			}
+
+			var result *gorm.DB
+			if ok {
+				result = tx.Model(&models.Video{}).Where("id = ?", segment.ID()).Update("position", position)
+			} else {
+				video := videoFromSegment(segment, ch.Name, position)
+				result = tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"seg_start", "seg_end", "play_count", "likes", "position", "updated_at"}),
+				}).Create(&video)
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to persist Merry-Go: %v\n", err)
+		return
+	}
+	ch.savedPositions = positions
+}
+
+/*
+onlyRotated riders 가 저장된 순서에서 회전하거나 영상이 빠지기만 했는지 여부
+
+Head부터 저장된 Position을 따라가면서 줄어드는 곳이 한 번 이하라면 순환 순서가 그대로입니다.
+*/
+func (ch *Channel) onlyRotated(riders []*data_struct.Segment) bool {
+	descents := 0
+	for i, segment := range riders {
+		position, ok := ch.savedPositions[segment.ID()]
+		if !ok {
+			return false
+		}
+		if next := ch.savedPositions[riders[(i+1)%len(riders)].ID()]; position > next {
+			descents++
+		}
+	}
+	return descents <= 1
+}
+
+// persistPlayed 재생이 끝난 영상의 재생 횟수와 재생이 끝난 시각을 DB에 저장합니다.
+func persistPlayed(segment *data_struct.Segment) {
+	result := database.DB.Model(&models.Video{}).Where("id = ?", segment.ID()).
+		Updates(map[string]any{"play_count": segment.PlayCount(), "played_at": time.Now()})
+	if result.Error != nil {
+		log.Printf("Failed to persist video %s: %v\n", segment.ID(), result.Error)
+	}
+}
+
+// persistLikes 영상의 좋아요 수를 DB에 저장합니다.
+func persistLikes(segment *data_struct.Segment) {
+	result := database.DB.Model(&models.Video{}).Where("id = ?", segment.ID()).Update("likes", segment.LikeCount())
+	if result.Error != nil {
+		log.Printf("Failed to persist video %s: %v\n", segment.ID(), result.Error)
+	}
+}
+
+// deleteVideoRecord DB에서 id에 해당하는 영상 메타데이터를 삭제합니다.
+func deleteVideoRecord(id string) {
+	result := database.DB.Delete(&models.Video{}, "id = ?", id)
+	if result.Error != nil {
+		log.Printf("Failed to delete video record %s: %v\n", id, result.Error)
+	}
+}
+
+/*
+loadFromDatabase DB에 저장된 채널의 영상들을 Position 순서대로 Merry-Go에 태우고, 디스크의 파일과 맞춰봅니다.
+마지막으로 재생이 끝난 영상의 다음 영상이 Head가 되도록 회전시켜서 이어서 재생합니다.
+
+segment 파일이 빠진 영상은 DB에서 제거하고, 어떤 영상에도 속하지 않는 영상 디렉토리는 삭제합니다.
+
+return: DB에 저장된 영상이 있었는지 여부 bool, 에러 error
+*/
+func (ch *Channel) loadFromDatabase() (bool, error) {
+	var videos []models.Video
+	result := database.DB.Where("channel = ?", ch.Name).Order("position asc").Find(&videos)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if len(videos) == 0 {
+		return false, nil
+	}
+
+	// 실행 중에 용량을 늘렸던 경우를 위해 저장된 영상 수에 맞춰 용량 확장
+	if len(videos) > ch.merryGo.Cap() {
+		if err := ch.merryGo.Resize(len(videos)); err != nil {
+			return true, err
+		}
+	}
+
+	lastPlayed := models.Video{}
+	for _, video := range videos {
+		segment, err := segmentFromVideo(video)
+		if err != nil {
+			log.Printf("[Load] %v\n", err)
+			deleteVideoRecord(video.Id)
+			continue
+		}
+
+		// 이전 버전의 segment 파일 구조라면 영상 디렉토리로 옮김
+		migrated := false
+		if _, err := os.Stat(filepath.Join(ch.hlsDir, video.Id)); os.IsNotExist(err) {
+			migrated = true
+			if err := ch.migrateLegacySegments(segment); err != nil {
+				log.Printf("[Load] %v\n", err)
+			}
+			start, end := segment.Info()
+			video.SegStart = start
+			video.SegEnd = end
+		}
+
+		if !ch.videoFilesExist(segment) {
+			log.Printf("[Load] %s 영상의 segment 파일이 없어 제거합니다.\n", video.Id)
+			deleteVideoRecord(video.Id)
+			continue
+		}
+
+		err = ch.merryGo.Append(segment)
+		if err != nil {
+			return true, err
+		}
+		// 옮긴 영상은 바뀐 segment 구간까지 다시 저장되도록 저장된 순서에서 제외
+		if !migrated {
+			ch.savedPositions[video.Id] = video.Position
+		}
+		if video.PlayedAt.After(lastPlayed.PlayedAt) {
+			lastPlayed = video
+		}
+		log.Printf("Merry-Go %d 번째 데이터 : %d, %d, %f", ch.merryGo.Len(), video.SegStart, video.SegEnd, video.Duration)
+	}
+
+	if lastPlayed.Id != "" {
+		for range ch.merryGo.Len() {
+			head, _ := ch.merryGo.Peek()
+			_ = ch.merryGo.Rotate()
+			if head.ID() == lastPlayed.Id {
+				break
+			}
+		}
+	}
+
+	ch.removeOrphanVideos()
+	ch.persistMerryGo()
+
+	return true, nil
+}
+
+// videoFilesExist 영상 디렉토리에 모든 화질의 segment 파일이 존재하는지 확인합니다.
+func (ch *Channel) videoFilesExist(segment *data_struct.Segment) bool {
+	start, end := segment.Info()
+	renditionDirs := segment.Renditions
+	if len(renditionDirs) == 0 {
+		renditionDirs = []string{""}
+	}
+
+	for _, rendition := range renditionDirs {
+		for i := start; i <= end; i++ {
+			if _, err := os.Stat(filepath.Join(ch.hlsDir, segment.ID(), rendition, fmt.Sprintf(SEGNAME+"%d.ts", i))); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/*
+migrateLegacySegments 이전 버전처럼 hls 디렉토리 바로 아래에 seg1.ts, seg2.ts ... 로 저장된 영상을
+영상 디렉토리 <videoID>/seg0.ts ... 구조로 옮기고 segment 구간을 0부터 다시 매깁니다.
+*/
+func (ch *Channel) migrateLegacySegments(segment *data_struct.Segment) error {
+	videoDir := filepath.Join(ch.hlsDir, segment.ID())
+	if err := os.MkdirAll(videoDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	start, end := segment.Info()
+	for i := start; i <= end; i++ {
+		sourceFilePath := filepath.Join(ch.hlsDir, fmt.Sprintf(SEGNAME+"%d.ts", i))
+		destFilePath := filepath.Join(videoDir, fmt.Sprintf(SEGNAME+"%d.ts", i-start))
+		if err := os.Rename(sourceFilePath, destFilePath); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", sourceFilePath, err)
+		}
+	}
+	log.Printf("[Load] %s 영상을 영상 디렉토리로 옮겼습니다.\n", segment.ID())
+
+	segment.Start = 0
+	segment.End = end - start
+	return nil
+}
+
+// removeOrphanVideos 채널 Merry-Go의 어떤 영상에도 속하지 않는 영상 디렉토리와 이전 버전의 segment 파일을 삭제합니다.
+func (ch *Channel) removeOrphanVideos() {
+	files, err := os.ReadDir(ch.hlsDir)
+	if err != nil {
+		return
+	}
+
+	used := map[string]bool{}
+	for segment := range ch.merryGo.Values() {
+		used[segment.ID()] = true
+	}
+
+	legacySegment := regexp.MustCompile(`^` + SEGNAME + `\d+\.ts$`)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			// 업로드 시 생성된 UUID 이름의 디렉토리만 대상
+			if _, err := uuid.Parse(strings.TrimSuffix(name, ".tmp")); err != nil || used[name] {
+				continue
+			}
+		} else if !legacySegment.MatchString(name) {
+			continue
+		}
+
+		log.Printf("[Load] 사용되지 않는 파일 삭제: %s\n", name)
+		if err := os.RemoveAll(filepath.Join(ch.hlsDir, name)); err != nil {
+			log.Printf("Failed to delete %s: %v\n", name, err)
+		}
+	}
+}
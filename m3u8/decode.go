@@ -0,0 +1,152 @@
+package m3u8
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadFile 파일에서 미디어 플레이리스트를 읽어옵니다.
+func ReadFile(path string) (*MediaPlaylist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Decode 바이트 배열에서 미디어 플레이리스트를 읽어옵니다.
+func Decode(data []byte) (*MediaPlaylist, error) {
+	return Parse(bytes.NewReader(data))
+}
+
+/*
+Parse 미디어 플레이리스트를 읽어옵니다.
+
+첫번째 segment 관련 태그가 나오기 전까지의 알 수 없는 태그는 헤더 태그로, 그 이후의 알 수 없는 태그는 다음 segment의 태그로 보관합니다.
+'#EXT' 로 시작하지 않는 주석은 무시합니다.
+*/
+func Parse(r io.Reader) (*MediaPlaylist, error) {
+	playlist := &MediaPlaylist{}
+	scanner := bufio.NewScanner(r)
+
+	var segment Segment
+	inSegment := false // 현재 segment에 해당하는 태그를 읽는 중인지
+	lineNum := 0
+	headerSeen := false
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !headerSeen {
+			if line != TagHeader {
+				return nil, errors.New("m3u8: missing #EXTM3U header")
+			}
+			headerSeen = true
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			segment.URI = line
+			playlist.Segments = append(playlist.Segments, segment)
+			segment = Segment{}
+			inSegment = false
+			continue
+		}
+		if !strings.HasPrefix(line, "#EXT") {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		var err error
+		switch name {
+		case TagVersion:
+			playlist.Version, err = strconv.Atoi(value)
+		case TagTargetDuration:
+			playlist.TargetDuration, err = strconv.Atoi(value)
+		case TagMediaSequence:
+			playlist.MediaSequence, err = strconv.Atoi(value)
+		case TagDiscontinuitySequence:
+			playlist.DiscontinuitySequence, err = strconv.Atoi(value)
+		case TagPlaylistType:
+			playlist.PlaylistType = value
+		case TagEndList:
+			playlist.EndList = true
+		case TagInf:
+			inSegment = true
+			durationStr, title, _ := strings.Cut(value, ",")
+			segment.Duration, err = strconv.ParseFloat(strings.TrimSpace(durationStr), 64)
+			segment.Title = title
+		case TagDiscontinuity:
+			inSegment = true
+			segment.Discontinuity = true
+		case TagByteRange:
+			inSegment = true
+			segment.ByteRange, err = parseByteRange(value)
+		case TagProgramDateTime:
+			inSegment = true
+			segment.ProgramDateTime, err = parseProgramDateTime(value)
+		default:
+			tag := Tag{Name: name, Value: value}
+			if inSegment || len(playlist.Segments) > 0 {
+				segment.Tags = append(segment.Tags, tag)
+			} else {
+				playlist.Tags = append(playlist.Tags, tag)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("m3u8: line %d: invalid %s: %w", lineNum, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !headerSeen {
+		return nil, errors.New("m3u8: missing #EXTM3U header")
+	}
+
+	return playlist, nil
+}
+
+// ISO 8601에서 허용하는 시간대 형식, +09:00 과 +0900 (소수점 이하 초는 있어도 읽힘)
+var programDateTimeLayouts = []string{"2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05Z0700"}
+
+// parseProgramDateTime #EXT-X-PROGRAM-DATE-TIME 값을 읽습니다.
+func parseProgramDateTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range programDateTimeLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parseByteRange <Length>[@<Offset>] 형식의 값을 읽습니다.
+func parseByteRange(value string) (*ByteRange, error) {
+	lengthStr, offsetStr, hasOffset := strings.Cut(value, "@")
+	length, err := strconv.ParseInt(lengthStr, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	byteRange := &ByteRange{Length: length, HasOffset: hasOffset}
+	if hasOffset {
+		byteRange.Offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return byteRange, nil
+}
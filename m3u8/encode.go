@@ -0,0 +1,91 @@
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Encode 미디어 플레이리스트를 m3u8 형식으로 작성합니다.
+func (p *MediaPlaylist) Encode() []byte {
+	var buffer bytes.Buffer
+	_, _ = p.WriteTo(&buffer)
+	return buffer.Bytes()
+}
+
+func (p *MediaPlaylist) String() string {
+	return string(p.Encode())
+}
+
+// WriteTo 미디어 플레이리스트를 m3u8 형식으로 w 에 씁니다.
+func (p *MediaPlaylist) WriteTo(w io.Writer) (int64, error) {
+	ew := &errWriter{w: w}
+
+	ew.line(TagHeader)
+	if p.Version > 0 {
+		ew.line(fmt.Sprintf("%s:%d", TagVersion, p.Version))
+	}
+	ew.line(fmt.Sprintf("%s:%d", TagTargetDuration, p.TargetDuration))
+	if p.PlaylistType != "" {
+		ew.line(TagPlaylistType + ":" + p.PlaylistType)
+	}
+	ew.line(fmt.Sprintf("%s:%d", TagMediaSequence, p.MediaSequence))
+	if p.DiscontinuitySequence > 0 {
+		ew.line(fmt.Sprintf("%s:%d", TagDiscontinuitySequence, p.DiscontinuitySequence))
+	}
+	for _, tag := range p.Tags {
+		ew.tag(tag)
+	}
+
+	for _, segment := range p.Segments {
+		if segment.Discontinuity {
+			ew.line(TagDiscontinuity)
+		}
+		if !segment.ProgramDateTime.IsZero() {
+			ew.line(TagProgramDateTime + ":" + segment.ProgramDateTime.Format(ProgramDateTimeFormat))
+		}
+		for _, tag := range segment.Tags {
+			ew.tag(tag)
+		}
+		ew.line(TagInf + ":" + strconv.FormatFloat(segment.Duration, 'f', 6, 64) + "," + segment.Title)
+		if segment.ByteRange != nil {
+			if segment.ByteRange.HasOffset {
+				ew.line(fmt.Sprintf("%s:%d@%d", TagByteRange, segment.ByteRange.Length, segment.ByteRange.Offset))
+			} else {
+				ew.line(fmt.Sprintf("%s:%d", TagByteRange, segment.ByteRange.Length))
+			}
+		}
+		ew.line(segment.URI)
+	}
+
+	if p.EndList {
+		ew.line(TagEndList)
+	}
+
+	return ew.n, ew.err
+}
+
+// errWriter 처음 발생한 에러를 기억하고 이후의 쓰기는 무시하는 writer
+type errWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (ew *errWriter) line(s string) {
+	if ew.err != nil {
+		return
+	}
+	n, err := io.WriteString(ew.w, s+"\n")
+	ew.n += int64(n)
+	ew.err = err
+}
+
+func (ew *errWriter) tag(tag Tag) {
+	if tag.Value == "" {
+		ew.line(tag.Name)
+		return
+	}
+	ew.line(tag.Name + ":" + tag.Value)
+}
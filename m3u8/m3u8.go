@@ -0,0 +1,89 @@
+/*
+Package m3u8 HLS 미디어 플레이리스트(.m3u8)를 구조체로 읽고 쓰는 패키지
+*/
+package m3u8
+
+import "time"
+
+const (
+	TagHeader                = "#EXTM3U"
+	TagVersion               = "#EXT-X-VERSION"
+	TagTargetDuration        = "#EXT-X-TARGETDURATION"
+	TagMediaSequence         = "#EXT-X-MEDIA-SEQUENCE"
+	TagDiscontinuitySequence = "#EXT-X-DISCONTINUITY-SEQUENCE"
+	TagPlaylistType          = "#EXT-X-PLAYLIST-TYPE"
+	TagEndList               = "#EXT-X-ENDLIST"
+	TagInf                   = "#EXTINF"
+	TagDiscontinuity         = "#EXT-X-DISCONTINUITY"
+	TagByteRange             = "#EXT-X-BYTERANGE"
+	TagProgramDateTime       = "#EXT-X-PROGRAM-DATE-TIME"
+	TagAllowCache            = "#EXT-X-ALLOW-CACHE"
+)
+
+// ProgramDateTimeFormat #EXT-X-PROGRAM-DATE-TIME 값의 형식 (ISO 8601, 밀리초)
+const ProgramDateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Tag 구조체로 따로 다루지 않는 태그, 읽은 그대로 다시 씀
+type Tag struct {
+	Name  string // ex) #EXT-X-ALLOW-CACHE
+	Value string // ':' 뒤의 값, 값이 없는 태그는 빈 문자열
+}
+
+// ByteRange #EXT-X-BYTERANGE:<Length>[@<Offset>]
+type ByteRange struct {
+	Length    int64
+	Offset    int64
+	HasOffset bool
+}
+
+// Segment 미디어 플레이리스트의 segment 하나
+type Segment struct {
+	URI             string
+	Duration        float64 // #EXTINF 길이 (초)
+	Title           string  // #EXTINF 의 ',' 뒤 제목
+	Discontinuity   bool    // 앞에 #EXT-X-DISCONTINUITY 가 붙는지 여부
+	ByteRange       *ByteRange
+	ProgramDateTime time.Time // 값이 없으면 zero
+	Tags            []Tag     // segment 앞에 붙은 그 외의 태그
+}
+
+// MediaPlaylist 미디어 플레이리스트
+type MediaPlaylist struct {
+	Version               int // 0 이면 쓰지 않음
+	TargetDuration        int
+	MediaSequence         int
+	DiscontinuitySequence int
+	PlaylistType          string // VOD, EVENT 또는 빈 문자열
+	EndList               bool
+	Tags                  []Tag // 헤더에 있는 그 외의 태그
+	Segments              []Segment
+}
+
+// Duration 모든 segment 길이의 합 (초)
+func (p *MediaPlaylist) Duration() float64 {
+	total := 0.0
+	for _, segment := range p.Segments {
+		total += segment.Duration
+	}
+	return total
+}
+
+// Durations 각 segment의 길이 목록
+func (p *MediaPlaylist) Durations() []float64 {
+	durations := make([]float64, len(p.Segments))
+	for i, segment := range p.Segments {
+		durations[i] = segment.Duration
+	}
+	return durations
+}
+
+// MaxDuration 가장 긴 segment의 길이 (초)
+func (p *MediaPlaylist) MaxDuration() float64 {
+	maxDuration := 0.0
+	for _, segment := range p.Segments {
+		if segment.Duration > maxDuration {
+			maxDuration = segment.Duration
+		}
+	}
+	return maxDuration
+}
@@ -0,0 +1,253 @@
+package m3u8
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inUTC 시간대 표현만 다른 #EXT-X-PROGRAM-DATE-TIME 을 비교할 수 있도록 UTC로 바꾼 복사본
+func inUTC(p *MediaPlaylist) *MediaPlaylist {
+	copied := *p
+	copied.Segments = make([]Segment, len(p.Segments))
+	for i, segment := range p.Segments {
+		if !segment.ProgramDateTime.IsZero() {
+			segment.ProgramDateTime = segment.ProgramDateTime.UTC()
+		}
+		copied.Segments[i] = segment
+	}
+	return &copied
+}
+
+func TestMediaPlaylistRoundTrip(t *testing.T) {
+	seoul := time.FixedZone("", 9*60*60)
+
+	tests := []struct {
+		name  string
+		input string
+		want  *MediaPlaylist
+	}{
+		{
+			name: "vod with titles and endlist",
+			input: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:10
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-MEDIA-SEQUENCE:0
+#EXTINF:9.009000,first
+seg0.ts
+#EXTINF:3.500000,
+seg1.ts
+#EXT-X-ENDLIST
+`,
+			want: &MediaPlaylist{
+				Version:        3,
+				TargetDuration: 10,
+				PlaylistType:   "VOD",
+				EndList:        true,
+				Segments: []Segment{
+					{URI: "seg0.ts", Duration: 9.009, Title: "first"},
+					{URI: "seg1.ts", Duration: 3.5},
+				},
+			},
+		},
+		{
+			name: "live with discontinuity and program date time",
+			input: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:10
+#EXT-X-MEDIA-SEQUENCE:42
+#EXT-X-DISCONTINUITY-SEQUENCE:7
+#EXT-X-ALLOW-CACHE:NO
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T12:00:00.000Z
+#EXTINF:10.000000,
+a/720p/seg3.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T21:00:10.500+09:00
+#EXTINF:10.000000,
+b/720p/seg0.ts
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T21:00:20.500+0900
+#EXTINF:10.000000,
+b/720p/seg1.ts
+`,
+			want: &MediaPlaylist{
+				Version:               3,
+				TargetDuration:        10,
+				MediaSequence:         42,
+				DiscontinuitySequence: 7,
+				Tags:                  []Tag{{Name: TagAllowCache, Value: "NO"}},
+				Segments: []Segment{
+					{URI: "a/720p/seg3.ts", Duration: 10, ProgramDateTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+					{URI: "b/720p/seg0.ts", Duration: 10, Discontinuity: true, ProgramDateTime: time.Date(2024, 5, 1, 21, 0, 10, 500e6, seoul)},
+					{URI: "b/720p/seg1.ts", Duration: 10, ProgramDateTime: time.Date(2024, 5, 1, 21, 0, 20, 500e6, seoul)},
+				},
+			},
+		},
+		{
+			name: "byterange with and without offset",
+			input: `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:0
+#EXTINF:4.000000,
+#EXT-X-BYTERANGE:1000@0
+video.ts
+#EXTINF:4.000000,
+#EXT-X-BYTERANGE:2000
+video.ts
+#EXT-X-ENDLIST
+`,
+			want: &MediaPlaylist{
+				Version:        4,
+				TargetDuration: 4,
+				EndList:        true,
+				Segments: []Segment{
+					{URI: "video.ts", Duration: 4, ByteRange: &ByteRange{Length: 1000, Offset: 0, HasOffset: true}},
+					{URI: "video.ts", Duration: 4, ByteRange: &ByteRange{Length: 2000}},
+				},
+			},
+		},
+		{
+			name: "unknown header and segment tags",
+			input: `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-START:TIME-OFFSET=-12.0
+# plain comment is ignored
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXTINF:6.000000,
+seg1.ts
+#EXT-X-GAP
+#EXTINF:6.000000,
+seg2.ts
+`,
+			want: &MediaPlaylist{
+				TargetDuration: 6,
+				MediaSequence:  1,
+				Tags: []Tag{
+					{Name: "#EXT-X-INDEPENDENT-SEGMENTS"},
+					{Name: "#EXT-X-START", Value: "TIME-OFFSET=-12.0"},
+					{Name: "#EXT-X-KEY", Value: `METHOD=AES-128,URI="key.bin"`},
+				},
+				Segments: []Segment{
+					{URI: "seg1.ts", Duration: 6},
+					{URI: "seg2.ts", Duration: 6, Tags: []Tag{{Name: "#EXT-X-GAP"}}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := Decode([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got, want := inUTC(decoded), inUTC(tt.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Decode() = %+v, want %+v", got, want)
+			}
+
+			encoded := decoded.Encode()
+			redecoded, err := Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode(Encode()) error = %v\n%s", err, encoded)
+			}
+			if got, want := inUTC(redecoded), inUTC(decoded); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Decode(Encode()) = %+v, want %+v\n%s", got, want, encoded)
+			}
+			if again := redecoded.Encode(); string(again) != string(encoded) {
+				t.Fatalf("Encode() is not stable:\n%s\nthen\n%s", encoded, again)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing header", "#EXT-X-TARGETDURATION:10\nseg0.ts\n"},
+		{"empty", ""},
+		{"invalid extinf", "#EXTM3U\n#EXTINF:abc,\nseg0.ts\n"},
+		{"invalid byterange", "#EXTM3U\n#EXTINF:1,\n#EXT-X-BYTERANGE:10@x\nseg0.ts\n"},
+		{"invalid program date time", "#EXTM3U\n#EXT-X-PROGRAM-DATE-TIME:yesterday\n#EXTINF:1,\nseg0.ts\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.input)); err == nil {
+				t.Fatal("Decode() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMasterPlaylistRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *MasterPlaylist
+	}{
+		{
+			name: "variants with quoted attributes",
+			input: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-STREAM-INF:BANDWIDTH=2928000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",NAME="720p"
+playlist_720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS="mp4a.40.2",NAME="audio"
+playlist_audio.m3u8
+`,
+			want: &MasterPlaylist{
+				Version:             3,
+				IndependentSegments: true,
+				Variants: []Variant{
+					{URI: "playlist_720p.m3u8", Bandwidth: 2928000, Resolution: "1280x720", Codecs: "avc1.4d401f,mp4a.40.2", Name: "720p"},
+					{URI: "playlist_audio.m3u8", Bandwidth: 128000, Codecs: "mp4a.40.2", Name: "audio"},
+				},
+			},
+		},
+		{
+			name: "unknown tags are ignored",
+			input: `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="en"
+#EXT-X-STREAM-INF:BANDWIDTH=800000
+low.m3u8
+`,
+			want: &MasterPlaylist{
+				Variants: []Variant{{URI: "low.m3u8", Bandwidth: 800000}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeMaster([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("DecodeMaster() error = %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.want) {
+				t.Fatalf("DecodeMaster() = %+v, want %+v", decoded, tt.want)
+			}
+
+			encoded := decoded.Encode()
+			redecoded, err := DecodeMaster(encoded)
+			if err != nil {
+				t.Fatalf("DecodeMaster(Encode()) error = %v\n%s", err, encoded)
+			}
+			if !reflect.DeepEqual(redecoded, decoded) {
+				t.Fatalf("DecodeMaster(Encode()) = %+v, want %+v\n%s", redecoded, decoded, encoded)
+			}
+		})
+	}
+}
+
+func TestDecodeMasterURIWithoutStreamInf(t *testing.T) {
+	_, err := DecodeMaster([]byte("#EXTM3U\nplaylist_720p.m3u8\n"))
+	if err == nil || !strings.Contains(err.Error(), TagStreamInf) {
+		t.Fatalf("DecodeMaster() error = %v, want missing %s", err, TagStreamInf)
+	}
+}
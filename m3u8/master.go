@@ -0,0 +1,171 @@
+package m3u8
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	TagStreamInf           = "#EXT-X-STREAM-INF"
+	TagIndependentSegments = "#EXT-X-INDEPENDENT-SEGMENTS"
+)
+
+// Variant 마스터 플레이리스트의 #EXT-X-STREAM-INF 하나
+type Variant struct {
+	URI        string
+	Bandwidth  int    // BANDWIDTH, 최대 bit/s
+	Resolution string // RESOLUTION, ex) 1280x720, 없으면 빈 문자열
+	Codecs     string // CODECS, ex) avc1.4d401f,mp4a.40.2
+	Name       string // NAME, 없으면 빈 문자열
+}
+
+// MasterPlaylist 화질별 미디어 플레이리스트를 묶는 마스터 플레이리스트
+type MasterPlaylist struct {
+	Version             int // 0 이면 쓰지 않음
+	IndependentSegments bool
+	Variants            []Variant
+}
+
+// DecodeMaster 바이트 배열에서 마스터 플레이리스트를 읽어옵니다.
+func DecodeMaster(data []byte) (*MasterPlaylist, error) {
+	return ParseMaster(bytes.NewReader(data))
+}
+
+/*
+ParseMaster 마스터 플레이리스트를 읽어옵니다. #EXT-X-STREAM-INF 외의 알 수 없는 태그는 무시합니다.
+*/
+func ParseMaster(r io.Reader) (*MasterPlaylist, error) {
+	playlist := &MasterPlaylist{}
+	scanner := bufio.NewScanner(r)
+
+	var variant *Variant
+	lineNum := 0
+	headerSeen := false
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !headerSeen {
+			if line != TagHeader {
+				return nil, errors.New("m3u8: missing #EXTM3U header")
+			}
+			headerSeen = true
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			if variant == nil {
+				return nil, fmt.Errorf("m3u8: line %d: URI without %s", lineNum, TagStreamInf)
+			}
+			variant.URI = line
+			playlist.Variants = append(playlist.Variants, *variant)
+			variant = nil
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		var err error
+		switch name {
+		case TagVersion:
+			playlist.Version, err = strconv.Atoi(value)
+		case TagIndependentSegments:
+			playlist.IndependentSegments = true
+		case TagStreamInf:
+			variant = &Variant{}
+			attributes := parseAttributes(value)
+			variant.Bandwidth, err = strconv.Atoi(attributes["BANDWIDTH"])
+			variant.Resolution = attributes["RESOLUTION"]
+			variant.Codecs = attributes["CODECS"]
+			variant.Name = attributes["NAME"]
+		}
+		if err != nil {
+			return nil, fmt.Errorf("m3u8: line %d: invalid %s: %w", lineNum, name, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !headerSeen {
+		return nil, errors.New("m3u8: missing #EXTM3U header")
+	}
+
+	return playlist, nil
+}
+
+// parseAttributes KEY=VALUE,KEY="VALUE" 형식의 속성 목록을 읽습니다. 따옴표 안의 ',' 는 구분자로 보지 않습니다.
+func parseAttributes(value string) map[string]string {
+	attributes := map[string]string{}
+	for value != "" {
+		key, rest, found := strings.Cut(value, "=")
+		if !found {
+			break
+		}
+
+		var attribute string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				attribute, rest = rest[1:], ""
+			} else {
+				attribute, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			attribute, rest, _ = strings.Cut(rest, ",")
+		}
+
+		attributes[strings.TrimSpace(key)] = attribute
+		value = rest
+	}
+	return attributes
+}
+
+// Encode 마스터 플레이리스트를 m3u8 형식으로 작성합니다.
+func (p *MasterPlaylist) Encode() []byte {
+	var buffer bytes.Buffer
+	_, _ = p.WriteTo(&buffer)
+	return buffer.Bytes()
+}
+
+func (p *MasterPlaylist) String() string {
+	return string(p.Encode())
+}
+
+// WriteTo 마스터 플레이리스트를 m3u8 형식으로 w 에 씁니다.
+func (p *MasterPlaylist) WriteTo(w io.Writer) (int64, error) {
+	ew := &errWriter{w: w}
+
+	ew.line(TagHeader)
+	if p.Version > 0 {
+		ew.line(fmt.Sprintf("%s:%d", TagVersion, p.Version))
+	}
+	if p.IndependentSegments {
+		ew.line(TagIndependentSegments)
+	}
+
+	for _, variant := range p.Variants {
+		attributes := []string{fmt.Sprintf("BANDWIDTH=%d", variant.Bandwidth)}
+		if variant.Resolution != "" {
+			attributes = append(attributes, "RESOLUTION="+variant.Resolution)
+		}
+		if variant.Codecs != "" {
+			attributes = append(attributes, `CODECS="`+variant.Codecs+`"`)
+		}
+		if variant.Name != "" {
+			attributes = append(attributes, `NAME="`+variant.Name+`"`)
+		}
+		ew.line(TagStreamInf + ":" + strings.Join(attributes, ","))
+		ew.line(variant.URI)
+	}
+
+	return ew.n, ew.err
+}
@@ -24,7 +24,10 @@ func main() {
 	app := fiber.New(fiber.Config{
 		BodyLimit: 10 * 1024 * 1024, // 10MB
 	})
-	app.Use(cors.New())
+	app.Use(cors.New(cors.Config{
+		// 이어 올리기(tus) 클라이언트가 읽어야 하는 응답 헤더
+		ExposeHeaders: "Location,Upload-Offset,Upload-Length,Upload-Job,Tus-Resumable,Tus-Version,Tus-Extension,Tus-Max-Size,Tus-Checksum-Algorithm",
+	}))
 	app.Use(logger.New())
 
 	// 환경 변수 읽기
@@ -40,41 +43,53 @@ func main() {
 		return
 	}
 
-	// 메세지 전달용 웹소켓 실행
-	go handlers.HandleMessages()
+	// 채널별 Merry-Go, 채팅, 픽셀 보드 준비, 파일 업로드 방식이면 저장된 채널과 Playlist를 불러와서 회전 시작
+	err = handlers.InitChannels(!mode)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// 웹 소켓 핸들러 설정
 	app.Get("/ws", websocket.New(handlers.HandleConnections))
 
+	// 업로드 완료 등 서버 알림용 웹소켓
+	go handlers.HandleEvents()
+	app.Get("/wse", websocket.New(handlers.HandleEventConnections))
+
 	/////////////////////////////////////////////////////// 카메라에서 다이렉트로 전송 받는 경우
 
 	if mode {
 		// 서버 시작 시 Camera 업로드를 위한 ffmpeg 실행
 		go handlers.StartFfmpeg()
 		// 픽셀 보드 관련 소켓 연결 설정
-		go handlers.HandlePixelMessages()
 		app.Get("/wsp", websocket.New(handlers.HandlePixelConnections))
 	} else {
-		// Load Playlist
-		err = handlers.LoadHls()
-		if err != nil {
-			log.Fatal(err)
-		}
-		// 비디오 업로드 -> HLS 변환
-		app.Post("/uploadVideo", handlers.UploadHandler)
-
-		// 고루틴에서 주기적으로 인터벌 함수 실행
-		go handlers.RotateInteval()
+		// 비디오 업로드 -> 작업 큐 -> HLS 변환
+		handlers.StartUploadWorkers()
+		// Merry-Go가 비어있을 때 대신 재생할 슬레이트 준비
+		go handlers.PrepareSlate()
+		app.Get("/jobs/:id", handlers.JobStatusHandler)
+
+		// 관리자 API
+		admin := app.Group("/admin", handlers.AdminAuth)
+		admin.Post("/channels", handlers.CreateChannelHandler)
+		admin.Delete("/channels/:channel", handlers.DeleteChannelHandler)
+		app.Get("/api/channels", handlers.ChannelListHandler)
+
+		// 기본 채널은 / 아래, 다른 채널은 /c/<채널 이름> 아래에 같은 API를 가짐
+		registerChannelRoutes(app, admin)
+		channel := app.Group("/c/:channel", handlers.ChannelExists)
+		channel.Get("/", handlers.FileServerHandler)
+		channel.Get("/ws", websocket.New(handlers.HandleConnections))
+		channel.Get("/wse", websocket.New(handlers.HandleEventConnections))
+		channel.Get("/wsp", websocket.New(handlers.HandlePixelConnections))
+		registerChannelRoutes(channel, channel.Group("/admin", handlers.AdminAuth))
 	}
 
 	///////////////////////////////////////////////////////
 
 	// HLS 파일이 있는 디렉토리를 설정합니다.
-	app.Use("/hls", func(c *fiber.Ctx) error {
-		c.Set("Access-Control-Allow-Origin", "*")
-		c.Set("Cache-Control", "no-cache")
-		return c.Next()
-	})
+	app.Use("/hls", handlers.HlsHeaders)
 	app.Static("/hls", "static/hls")
 
 	// HTML 파일이 있는 디렉토리를 설정하고, 로그를 추가합니다.
@@ -90,3 +105,35 @@ func main() {
 	}
 
 }
+
+// registerChannelRoutes 채널 하나의 업로드, 영상, 상태 API와 관리자 API를 등록합니다.
+func registerChannelRoutes(router fiber.Router, admin fiber.Router) {
+	router.Post("/uploadVideo", handlers.UploadHandler)
+	// 이어 올리기 업로드 (tus 1.0.0)
+	router.Options("/uploads", handlers.ResumableOptionsHandler)
+	router.Post("/uploads", handlers.ResumableCreateHandler)
+	router.Head("/uploads/:id", handlers.ResumableHeadHandler)
+	router.Patch("/uploads/:id", handlers.ResumablePatchHandler)
+	router.Delete("/uploads/:id", handlers.ResumableDeleteHandler)
+	// Merry-Go에서 영상 제거
+	router.Delete("/videos/:id", handlers.AdminAuth, handlers.DeleteVideoHandler)
+	router.Post("/videos/:id/like", handlers.LikeVideoHandler)
+	// Merry-Go 영상 목록 (썸네일 포함)
+	router.Get("/api/carousel", handlers.CarouselHandler)
+	// Merry-Go 상태 (용량, 영상 순서, 재생 중인 영상)
+	router.Get("/api/merrygo", handlers.MerryGoStatusHandler)
+	// 편성표
+	router.Get("/api/schedule", handlers.ScheduleHandler)
+
+	// 관리자 API
+	admin.Post("/merrygo/capacity", handlers.ResizeHandler)
+	admin.Post("/merrygo/move", handlers.MoveHandler)
+	admin.Post("/merrygo/pin", handlers.PinHandler)
+	admin.Delete("/merrygo/pin", handlers.UnpinHandler)
+	admin.Post("/merrygo/skip", handlers.SkipHandler)
+	admin.Post("/merrygo/pause", handlers.PauseHandler)
+	admin.Post("/merrygo/resume", handlers.ResumeHandler)
+	admin.Post("/merrygo/strategy", handlers.StrategyHandler)
+	admin.Post("/schedule", handlers.CreateScheduleHandler)
+	admin.Delete("/schedule/:id", handlers.DeleteScheduleHandler)
+}
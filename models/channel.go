@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Channel Merry-Go 채널의 설정, 기본 채널은 빈 이름으로 저장
+type Channel struct {
+	Name      string `gorm:"primaryKey"`
+	Capacity  int
+	Strategy  string  // 회전 방식 이름
+	MaxLength float64 // 업로드할 수 있는 영상의 최대 길이 (초), 0 이면 MAX_VIDEO_LENGTH
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
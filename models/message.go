@@ -4,6 +4,7 @@ import "gorm.io/gorm"
 
 type Message struct {
 	gorm.Model
+	Channel  string `gorm:"index"` // 채널 이름, 기본 채널은 빈 문자열
 	UserName string
 	Message  string
 }
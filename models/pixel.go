@@ -3,8 +3,9 @@ package models
 import "time"
 
 type Pixel struct {
-	Id        string `gorm:"primaryKey"`
-	Color     string `gorm:"size:7"` // 색상 값은 HEX 코드 (#RRGGBB)로 저장
+	Id        string `gorm:"primaryKey"` // 기본 채널은 픽셀 ID, 다른 채널은 <채널 이름>/<픽셀 ID>
+	Channel   string `gorm:"index"`      // 채널 이름, 기본 채널은 빈 문자열
+	Color     string `gorm:"size:7"`     // 색상 값은 HEX 코드 (#RRGGBB)로 저장
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
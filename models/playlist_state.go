@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+/*
+PlaylistState 라이브 플레이리스트의 sequence 값, 재시작 후에도 줄어들지 않도록 앞으로 쓸 값까지 미리 예약해서 저장
+
+고정한 영상도 재시작 후에 이어서 고정되도록 함께 저장합니다.
+*/
+type PlaylistState struct {
+	Name                  string `gorm:"primaryKey"`
+	MediaSequence         int
+	DiscontinuitySequence int
+	Pinned                string // 관리자가 고정한 영상 ID, 고정하지 않았으면 빈 문자열
+	UpdatedAt             time.Time
+}
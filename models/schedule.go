@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Schedule 채널의 편성 블록, 해당 시간 동안에는 블록의 영상만 회전
+type Schedule struct {
+	Id        uint   `gorm:"primaryKey"`
+	Channel   string `gorm:"index"` // 채널 이름, 기본 채널은 빈 문자열
+	Name      string
+	Start     string // 시작 시각 HH:MM
+	End       string // 끝 시각 HH:MM, 시작 시각보다 이르면 다음 날 끝 시각까지
+	Days      string // 요일 목록 ex) mon,tue,wed, 비어있으면 매일
+	Videos    string // 블록에서 재생할 영상 ID 목록 (JSON 배열)
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Video Merry-Go에 타고 있는 영상의 메타데이터
+type Video struct {
+	Id           string `gorm:"primaryKey"` // 업로드 시 생성된 UUID
+	Channel      string `gorm:"index"`      // 채널 이름, 기본 채널은 빈 문자열
+	SegStart     int
+	SegEnd       int
+	Durations    string  // 각 segment의 #EXTINF 길이 목록 (JSON 배열)
+	Duration     float64 // 전체 재생 길이 (초)
+	Renditions   string  // 변환된 화질 이름 목록 (JSON 배열), 비어있으면 이전 버전의 단일 화질
+	Uploader     string
+	OriginalName string
+	Loudness     float64 // 업로드 시 측정한 통합 음량 (LUFS), 측정하지 않았으면 0
+	PlayCount    int
+	Likes        int
+	Position     int       // Merry-Go 내 순환 순서, Head는 마지막으로 재생이 끝난 영상의 다음 영상
+	PlayedAt     time.Time // 마지막으로 재생이 끝난 시각
+	UploadedAt   time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,312 @@
+package playlist
+
+import (
+	"Merry-Go/m3u8"
+	"math"
+	"time"
+)
+
+// Segment 라이브 플레이리스트에 들어가는 segment 하나
+type Segment struct {
+	VideoID       string
+	URIs          map[string]string // 화질 이름별 segment 경로
+	Duration      float64
+	Discontinuity bool // 앞에 #EXT-X-DISCONTINUITY 태그가 붙는지 여부, 영상의 첫 segment
+}
+
+// Video 라이브 플레이리스트 뒤에 이어 붙일 영상 하나
+type Video struct {
+	ID        string
+	URIs      map[string][]string // 화질 이름별 segment 경로 목록, 모든 화질의 segment 수는 Durations와 같아야 함
+	Durations []float64
+}
+
+/*
+Live Merry-Go를 끝나지 않는 라이브 스트림으로 보고, 앞으로 재생될 segment 몇 개만 sliding window 로 보여주는 플레이리스트
+
+앞의 segment가 재생되어 빠질 때마다 #EXT-X-MEDIA-SEQUENCE 가 1씩 증가하고, 빠진 segment에 구분자 태그가 붙어 있었다면
+#EXT-X-DISCONTINUITY-SEQUENCE 도 1 증가합니다. 두 값은 줄어들지 않습니다.
+
+화질별 플레이리스트는 하나의 윈도우를 공유하므로 sequence 값과 segment 경계가 항상 같이 움직입니다.
+
+재생 시각은 맨 앞 segment가 재생되기 시작한 시각에 #EXTINF 길이를 그대로 더해서 계산하므로,
+회전이 조금 늦게 처리되더라도 오차가 쌓이지 않습니다. 각 segment에는 #EXT-X-PROGRAM-DATE-TIME 으로 재생 시각을 붙입니다.
+
+동시성 보호는 호출하는 쪽에서 해야 합니다.
+*/
+type Live struct {
+	size                  int
+	next                  func() (Video, bool)
+	segments              []Segment
+	mediaSequence         int
+	discontinuitySequence int
+	targetDuration        int
+	frontStart            time.Time // 맨 앞 segment가 재생되기 시작한 시각
+	drained               bool      // 직전 Advance로 윈도우가 비었는지 여부, 바로 이어서 채우면 재생 시각을 이어감
+}
+
+/*
+NewLive 라이브 플레이리스트를 생성합니다.
+
+size int: 윈도우에 유지할 segment 수
+next func() (Video, bool): 윈도우가 모자랄 때 다음에 이어 붙일 영상을 꺼내오는 함수, 없으면 false
+*/
+func NewLive(size int, next func() (Video, bool)) *Live {
+	if size < 1 {
+		size = 1
+	}
+	return &Live{size: size, next: next}
+}
+
+/*
+Restore 이전에 저장해둔 sequence 값에서 이어서 시작합니다. 윈도우가 비어있을 때만 호출해야 합니다.
+*/
+func (l *Live) Restore(mediaSequence int, discontinuitySequence int) {
+	l.mediaSequence = mediaSequence
+	l.discontinuitySequence = discontinuitySequence
+}
+
+// Sequence 현재 #EXT-X-MEDIA-SEQUENCE, #EXT-X-DISCONTINUITY-SEQUENCE 값
+func (l *Live) Sequence() (int, int) {
+	return l.mediaSequence, l.discontinuitySequence
+}
+
+// Size 윈도우에 유지할 segment 수
+func (l *Live) Size() int {
+	return l.size
+}
+
+// Len 윈도우에 들어있는 segment 수
+func (l *Live) Len() int {
+	return len(l.segments)
+}
+
+// Segments 윈도우에 들어있는 segment 목록의 복사본
+func (l *Live) Segments() []Segment {
+	segments := make([]Segment, len(l.segments))
+	copy(segments, l.segments)
+	return segments
+}
+
+// Front 지금 재생 중인 윈도우 맨 앞의 segment
+func (l *Live) Front() (Segment, bool) {
+	if len(l.segments) == 0 {
+		return Segment{}, false
+	}
+	return l.segments[0], true
+}
+
+// Contains 윈도우에 해당 영상의 segment가 남아있는지 확인합니다.
+func (l *Live) Contains(videoID string) bool {
+	for _, segment := range l.segments {
+		if segment.VideoID == videoID {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Fill 윈도우가 size 만큼 찰 때까지 next 로 영상을 꺼내서 뒤에 이어 붙입니다.
+
+return: 이어 붙인 영상 수 int
+*/
+func (l *Live) Fill() int {
+	// 쉬고 있다가 새로 채우는 경우 지금부터 재생 시작
+	if len(l.segments) == 0 && !l.drained {
+		l.frontStart = time.Now()
+	}
+	l.drained = false
+
+	added := 0
+	for len(l.segments) < l.size {
+		video, ok := l.next()
+		if !ok || len(video.Durations) == 0 {
+			break
+		}
+		for i, duration := range video.Durations {
+			uris := make(map[string]string, len(video.URIs))
+			for rendition, renditionURIs := range video.URIs {
+				if i < len(renditionURIs) {
+					uris[rendition] = renditionURIs[i]
+				}
+			}
+			l.segments = append(l.segments, Segment{
+				VideoID:       video.ID,
+				URIs:          uris,
+				Duration:      duration,
+				Discontinuity: i == 0,
+			})
+		}
+		added++
+	}
+	l.updateTargetDuration()
+	return added
+}
+
+/*
+DropQueued 지금 재생 중인 영상 뒤에 이어 붙였지만 아직 재생을 시작하지 않은 영상의 segment를 모두 뺍니다.
+Merry-Go의 순서가 바뀌었을 때 다음 Fill 에서 바뀐 순서로 다시 채우기 위해 사용하며, sequence 값은 바뀌지 않습니다.
+
+return: 뺀 영상의 ID 목록, 이어 붙인 순서대로
+*/
+func (l *Live) DropQueued() []string {
+	for i := 1; i < len(l.segments); i++ {
+		if !l.segments[i].Discontinuity {
+			continue
+		}
+
+		var dropped []string
+		for _, segment := range l.segments[i:] {
+			if segment.Discontinuity {
+				dropped = append(dropped, segment.VideoID)
+			}
+		}
+		l.segments = l.segments[:i]
+		return dropped
+	}
+	return nil
+}
+
+/*
+Advance 맨 앞의 segment 재생이 끝났을 때 호출하며, 해당 segment를 빼고 sequence 값을 증가시킵니다.
+
+return: 빠진 segment가 영상의 마지막 segment였다면 해당 영상의 ID와 true
+*/
+func (l *Live) Advance() (string, bool) {
+	if len(l.segments) == 0 {
+		return "", false
+	}
+
+	front := l.segments[0]
+	l.segments = l.segments[1:]
+	l.frontStart = l.frontStart.Add(seconds(front.Duration))
+	l.drained = len(l.segments) == 0
+	l.mediaSequence++
+	if front.Discontinuity {
+		l.discontinuitySequence++
+	}
+
+	finished := len(l.segments) == 0 || l.segments[0].Discontinuity
+	return front.VideoID, finished
+}
+
+/*
+Skip 지금 재생 중인 영상의 남은 segment를 모두 빼고, 다음 영상이 지금부터 재생되는 것으로 시각을 맞춥니다.
+
+return: 건너뛴 영상의 ID와 true, 윈도우가 비어있으면 false
+*/
+func (l *Live) Skip() (string, bool) {
+	if len(l.segments) == 0 {
+		return "", false
+	}
+
+	videoID := l.segments[0].VideoID
+	for {
+		if _, finished := l.Advance(); finished {
+			break
+		}
+	}
+	// 윈도우가 비었다면 다음 Fill 에서 지금부터 재생 시작
+	l.frontStart = time.Now()
+	l.drained = false
+	return videoID, true
+}
+
+// ResetClock 맨 앞 segment가 지금부터 다시 재생되는 것으로 시각을 맞춥니다. 멈췄다가 다시 시작할 때 사용합니다.
+func (l *Live) ResetClock() {
+	l.frontStart = time.Now()
+}
+
+/*
+Deadline 맨 앞 segment의 재생이 끝나는 시각, 이 시각에 Advance를 호출해야 합니다.
+
+return: 재생이 끝나는 시각 time.Time, 윈도우가 비어있으면 false
+*/
+func (l *Live) Deadline() (time.Time, bool) {
+	front, ok := l.Front()
+	if !ok {
+		return time.Time{}, false
+	}
+	return l.frontStart.Add(seconds(front.Duration)), true
+}
+
+/*
+End 윈도우의 마지막 segment 재생이 끝나는 시각, 다음에 이어 붙일 영상이 재생되기 시작하는 시각입니다.
+
+윈도우가 비어있으면 바로 재생되므로 지금 시각을 리턴합니다.
+*/
+func (l *Live) End() time.Time {
+	if len(l.segments) == 0 && !l.drained {
+		return time.Now()
+	}
+	end := l.frontStart
+	for _, segment := range l.segments {
+		end = end.Add(seconds(segment.Duration))
+	}
+	return end
+}
+
+/*
+CurrentVideo 지금 재생 중인 영상(맨 앞 segment의 영상)과 그 영상의 재생이 끝나는 시각
+
+영상은 모든 segment가 한번에 윈도우에 들어오므로, 다음 구분자 태그 전까지의 segment 길이를 더해서 계산합니다.
+
+return: 영상 ID string, 재생이 끝나는 시각 time.Time, 윈도우가 비어있으면 false
+*/
+func (l *Live) CurrentVideo() (string, time.Time, bool) {
+	if len(l.segments) == 0 {
+		return "", time.Time{}, false
+	}
+
+	end := l.frontStart
+	for i, segment := range l.segments {
+		if i > 0 && segment.Discontinuity {
+			break
+		}
+		end = end.Add(seconds(segment.Duration))
+	}
+	return l.segments[0].VideoID, end, true
+}
+
+// seconds #EXTINF 의 초 단위 길이를 time.Duration 으로 변환합니다.
+func seconds(duration float64) time.Duration {
+	return time.Duration(duration * float64(time.Second))
+}
+
+// updateTargetDuration #EXT-X-TARGETDURATION 은 도중에 줄어들면 안 되므로 가장 긴 segment 기준으로 늘리기만 합니다.
+func (l *Live) updateTargetDuration() {
+	for _, segment := range l.segments {
+		duration := int(math.Ceil(segment.Duration))
+		if duration > l.targetDuration {
+			l.targetDuration = duration
+		}
+	}
+}
+
+// Playlist 현재 윈도우를 rendition 화질의 미디어 플레이리스트로 변환합니다.
+func (l *Live) Playlist(rendition string) *m3u8.MediaPlaylist {
+	mediaPlaylist := &m3u8.MediaPlaylist{
+		Version:               3,
+		TargetDuration:        l.targetDuration,
+		MediaSequence:         l.mediaSequence,
+		DiscontinuitySequence: l.discontinuitySequence,
+		Tags:                  []m3u8.Tag{{Name: m3u8.TagAllowCache, Value: "NO"}},
+	}
+	programDateTime := l.frontStart
+	for _, segment := range l.segments {
+		mediaPlaylist.Segments = append(mediaPlaylist.Segments, m3u8.Segment{
+			URI:             segment.URIs[rendition],
+			Duration:        segment.Duration,
+			Discontinuity:   segment.Discontinuity,
+			ProgramDateTime: programDateTime,
+		})
+		programDateTime = programDateTime.Add(seconds(segment.Duration))
+	}
+	return mediaPlaylist
+}
+
+// Render 현재 윈도우를 rendition 화질의 m3u8 형식으로 작성합니다.
+func (l *Live) Render(rendition string) []byte {
+	return l.Playlist(rendition).Encode()
+}